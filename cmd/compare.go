@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/report"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var compareCfg struct {
+	thresholds   []string
+	outputFormat string
+}
+
+func newCompareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare baseline.json candidate.json",
+		Short: "Compare two benchmark results saved with --output-format json",
+		Long: "compare shows the difference between a baseline and a candidate benchmark for each " +
+			"metric, and exits with a non-zero status if any metric regresses by more than its threshold",
+		Example: "tsbenchmark compare --threshold p99=10 --threshold avg=5 baseline.json candidate.json",
+		RunE:    compare,
+		Args:    cobra.ExactArgs(2),
+	}
+
+	cmd.Flags().StringSliceVarP(&compareCfg.thresholds, "threshold", "t", nil,
+		"max allowed regression of a metric as a percentage, e.g. p99=10 (repeatable)")
+	cmd.Flags().StringVarP(&compareCfg.outputFormat, "output-format", "o", string(report.FormatText),
+		"output format: text, json, csv or markdown")
+
+	return cmd
+}
+
+// compare loads the baseline and candidate benchmarks, renders the delta of each metric and
+// returns an error if any configured threshold has been breached.
+func compare(cmd *cobra.Command, args []string) error {
+	thresholds, err := report.ParseThresholds(compareCfg.thresholds)
+	if err != nil {
+		return err
+	}
+
+	baseline, err := report.Load(args[0])
+	if err != nil {
+		return fmt.Errorf("error loading baseline: %w", err)
+	}
+
+	candidate, err := report.Load(args[1])
+	if err != nil {
+		return fmt.Errorf("error loading candidate: %w", err)
+	}
+
+	comparison, err := report.Compare(baseline, candidate, thresholds)
+	if err != nil {
+		return err
+	}
+
+	if err = report.RenderComparison(os.Stdout, comparison, report.Format(compareCfg.outputFormat)); err != nil {
+		return fmt.Errorf("error rendering comparison: %w", err)
+	}
+
+	if len(comparison.Breaches) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("regression thresholds breached: %s", strings.Join(comparison.Breaches, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/params"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	defaultGenerateRows   = 200
+	defaultGenerateHosts  = 10
+	defaultGenerateFrom   = "2017-01-01 00:00:00"
+	defaultGenerateTo     = "2017-01-03 00:00:00"
+	defaultGenerateWindow = time.Hour
+	defaultGenerateSeed   = 1
+)
+
+var generateCfg struct {
+	params.GenerateConfig
+	from       string
+	to         string
+	outputFile string
+}
+
+func newGenerateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a query params CSV file",
+		Long: "generate writes a query params CSV file with rows for random hosts, each querying a " +
+			"fixed size window that starts at a random time within the given range",
+		Example: "tsbenchmark generate --rows 10000 --hosts 100 --output-file query_params.csv",
+		RunE:    generate,
+		Args:    cobra.NoArgs,
+	}
+
+	cmd.Flags().IntVar(&generateCfg.Rows, "rows", defaultGenerateRows, "number of rows to generate")
+	cmd.Flags().IntVar(&generateCfg.Hosts, "hosts", defaultGenerateHosts, "number of distinct hosts to choose from")
+	cmd.Flags().StringVar(&generateCfg.from, "from", defaultGenerateFrom, "earliest start time of a query window")
+	cmd.Flags().StringVar(&generateCfg.to, "to", defaultGenerateTo, "latest start time of a query window")
+	cmd.Flags().DurationVar(&generateCfg.Window, "window", defaultGenerateWindow, "size of each query window")
+	cmd.Flags().Int64Var(&generateCfg.Seed, "seed", defaultGenerateSeed, "random seed, the same seed always generates the same file")
+	cmd.Flags().StringVarP(&generateCfg.outputFile, "output-file", "f", "", "write to a file instead of stdout")
+
+	return cmd
+}
+
+func generate(cmd *cobra.Command, args []string) error {
+	var err error
+	if generateCfg.From, err = time.Parse(params.TimestampLayout, generateCfg.from); err != nil {
+		return fmt.Errorf("invalid --from: must have layout %s", params.TimestampLayout)
+	}
+	if generateCfg.To, err = time.Parse(params.TimestampLayout, generateCfg.to); err != nil {
+		return fmt.Errorf("invalid --to: must have layout %s", params.TimestampLayout)
+	}
+
+	if err = generateCfg.Validate(); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if generateCfg.outputFile != "" {
+		file, err := os.Create(generateCfg.outputFile)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err = params.Generate(w, generateCfg.GenerateConfig); err != nil {
+		return fmt.Errorf("error generating query params: %w", err)
+	}
+
+	return nil
+}
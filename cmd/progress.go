@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressRefreshInterval = 500 * time.Millisecond
+	progressBarWidth        = 40
+)
+
+// progressDisplay periodically renders a live dashboard of the pool's progress to the terminal.
+type progressDisplay struct {
+	pool       *pool.Pool[queryResult]
+	maxWorkers int
+	rate       float64
+	label      string
+	area       *pterm.AreaPrinter
+	start      time.Time
+	stop       chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+}
+
+// progressEnabled reports whether the live progress display should be shown, which requires
+// stdout to be a terminal.
+func progressEnabled() bool {
+	return !cfg.NoProgress && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// startProgress starts the live progress display of a pool run with the given config. The label is
+// shown above the progress bar if not empty.
+func startProgress(queryPool *pool.Pool[queryResult], c config.Config, label string) (*progressDisplay, error) {
+	area, err := pterm.DefaultArea.WithRemoveWhenDone().Start()
+	if err != nil {
+		return nil, fmt.Errorf("error starting progress display: %w", err)
+	}
+
+	d := &progressDisplay{
+		pool:       queryPool,
+		maxWorkers: c.MaxWorkers,
+		rate:       c.Rate,
+		label:      label,
+		area:       area,
+		start:      time.Now(),
+		stop:       make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go d.run()
+
+	return d, nil
+}
+
+func (d *progressDisplay) run() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(progressRefreshInterval)
+	defer ticker.Stop()
+
+	last := d.pool.Stats()
+	lastTime := time.Now()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			stats := d.pool.Stats()
+			qps := float64(stats.Completed-last.Completed) / now.Sub(lastTime).Seconds()
+			d.area.Update(d.render(stats, qps))
+			last, lastTime = stats, now
+		}
+	}
+}
+
+func (d *progressDisplay) render(stats pool.Stats, qps float64) string {
+	var ratio float64
+	if stats.Submitted > 0 {
+		ratio = float64(stats.Completed) / float64(stats.Submitted)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+
+	var header string
+	if d.label != "" {
+		header = pterm.Bold.Sprint(d.label) + "\n"
+	}
+
+	var lag string
+	if d.rate > 0 {
+		lag = fmt.Sprintf("%s %.1f  %s %s\n", pterm.Green("Target QPS:"), d.rate,
+			pterm.Green("Schedule lag:"), stats.ScheduleLag.Round(time.Millisecond))
+	}
+
+	return header + fmt.Sprintf(
+		"%s %s %d/%d queries completed\n"+
+			"%s %.1f  %s %s  %s %s  %s %d  %s %d/%d (running %d)  %s %s\n%s",
+		pterm.Green("Progress:"), bar, stats.Completed, stats.Submitted,
+		pterm.Green("QPS:"), qps,
+		pterm.Green("P50:"), stats.P50,
+		pterm.Green("P99:"), stats.P99,
+		pterm.Green("Errors:"), stats.Errors,
+		pterm.Green("Active workers:"), stats.BusyWorkers, d.maxWorkers, stats.Workers,
+		pterm.Green("Elapsed:"), time.Since(d.start).Round(time.Second),
+		lag,
+	)
+}
+
+// Stop stops refreshing the dashboard and removes it from the terminal. It is safe to call more
+// than once.
+func (d *progressDisplay) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		d.wg.Wait()
+		_ = d.area.Stop()
+	})
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/internal/csv"
+	"github.com/joshjon/tsbenchmark/internal/db"
+	"github.com/joshjon/tsbenchmark/internal/rate"
+	"github.com/joshjon/tsbenchmark/internal/rawlog"
+	"github.com/joshjon/tsbenchmark/internal/report"
+	"github.com/joshjon/tsbenchmark/internal/usage"
+	"github.com/joshjon/tsbenchmark/internal/verify"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"github.com/joshjon/tsbenchmark/pkg/stats"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxWorkers         = 10
+	defaultWorkerQueueSize    = 50
+	defaultWaitQueueSize      = 500
+	defaultReaderBufferSize   = 500
+	defaultDBConn             = "host=timescaledb port=5432 user=postgres database=homework"
+	defaultHistogramMax       = stats.DefaultMaxValue
+	defaultHistogramPrecision = stats.DefaultSignificantFigures
+	defaultOutputFormat       = report.FormatText
+	defaultDrainTimeout       = 10 * time.Second
+	defaultArrival            = rate.Constant
+	defaultRouting            = pool.RoutingSticky
+	defaultRampSteps          = 5
+	defaultStepDuration       = 30 * time.Second
+)
+
+// configFlag is the flag used to load a YAML or TOML config file.
+const configFlag = "config"
+
+var defaultPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+var cfg config.Config
+
+// successPrinter and warningPrinter write status lines to stderr, so that they are not mixed into
+// machine-readable output written to stdout.
+var (
+	successPrinter = pterm.Success.WithWriter(os.Stderr)
+	warningPrinter = pterm.Warning.WithWriter(os.Stderr)
+)
+
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run csv_file",
+		Short: "Benchmark the queries generated from a query params CSV file",
+		Long: "run reads hostname, start time and end time rows from a CSV file and executes a query for each " +
+			"row across multiple concurrent workers, then outputs a summary of query performance",
+		RunE: run,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+	}
+
+	cmd.Flags().String(configFlag, "", "load flags from a .yaml or .toml config file, overridden by TSBENCH_* env vars and flags")
+	cmd.Flags().IntVarP(&cfg.MaxWorkers, "max-workers", "m", defaultMaxWorkers, "max number of concurrent workers")
+	cmd.Flags().IntVarP(&cfg.WorkerQueueSize, "worker-size", "s", defaultWorkerQueueSize, "size of each worker queue")
+	cmd.Flags().IntVarP(&cfg.WaitQueueSize, "wait-size", "w", defaultWaitQueueSize, "size of the wait queue")
+	cmd.Flags().IntVarP(&cfg.ReaderBufferSize, "reader-size", "r", defaultReaderBufferSize, "size of the file reader buffer")
+	cmd.Flags().StringVarP(&cfg.DatabaseConnection, "dbconn", "c", defaultDBConn, "host=x user=x port=x database=x (set the password with PGPASSWORD)")
+	cmd.Flags().Float64SliceVarP(&cfg.Percentiles, "percentiles", "p", defaultPercentiles, "query time percentiles to report")
+	cmd.Flags().IntVar(&cfg.HistogramPrecision, "histogram-precision", defaultHistogramPrecision, "significant figures (1-5) of recorded query times")
+	cmd.Flags().DurationVar(&cfg.HistogramMaxValue, "histogram-max", defaultHistogramMax, "max trackable query time, longer queries are clamped")
+	cmd.Flags().StringVarP(&cfg.OutputFormat, "output-format", "o", string(defaultOutputFormat), "output format: text, json, csv or markdown")
+	cmd.Flags().StringVarP(&cfg.OutputFile, "output-file", "f", "", "write benchmark results to a file instead of stdout")
+	cmd.Flags().BoolVar(&cfg.PerWorker, "per-worker", false, "include a per-worker breakdown in the output")
+	cmd.Flags().IntVar(&cfg.TopHosts, "top-hosts", 0, "include the N hosts with the slowest p99 query time in the output")
+	cmd.Flags().DurationVar(&cfg.TimelineInterval, "timeline-interval", 0, "record a timeline of query stats bucketed by this interval (e.g. 1s)")
+	cmd.Flags().StringVar(&cfg.TimelineFile, "timeline-file", "", "export the timeline to a .json or .csv file")
+	cmd.Flags().BoolVar(&cfg.NoProgress, "no-progress", false, "disable the live progress display")
+	cmd.Flags().DurationVar(&cfg.Duration, "duration", 0, "replay the query file until the duration elapses (e.g. 10m)")
+	cmd.Flags().IntVar(&cfg.Iterations, "iterations", 0, "replay the query file N times (default 1, or unlimited with --duration)")
+	cmd.Flags().Float64Var(&cfg.Rate, "rate", 0, "issue queries open-loop at a target rate of queries per second instead of as fast as possible")
+	cmd.Flags().StringVar(&cfg.Arrival, "arrival", defaultArrival, "arrival distribution of queries with --rate: constant or poisson")
+	cmd.Flags().StringVar(&cfg.Routing, "routing", defaultRouting, "how queries are routed to workers: sticky, hash, round-robin, least-loaded or random")
+	cmd.Flags().BoolVar(&cfg.WorkStealing, "work-stealing", false, "let idle workers execute queries backed up on other workers, relaxing host affinity")
+	cmd.Flags().DurationVar(&cfg.IdleTimeout, "idle-timeout", 0, "retire workers that wait this long for a query, releasing their hosts (e.g. 30s)")
+	cmd.Flags().IntVar(&cfg.MinWorkers, "min-workers", 0, "min number of workers kept running with --idle-timeout")
+	cmd.Flags().StringVar(&cfg.RawLog, "raw-log", "", "record every query execution to a .csv or .jsonl file")
+	cmd.Flags().StringVar(&cfg.ExpectedResults, "expected-results", "", "verify the row count of each query against the --raw-log of a previous run")
+	cmd.Flags().StringVar(&cfg.ReferenceDatabase, "reference-dbconn", "", "verify the results of each query against a reference database")
+	cmd.Flags().StringVar(&cfg.Ramp, "ramp", "", "ramp the load in steps to find the saturation point: workers or rate")
+	cmd.Flags().Float64Var(&cfg.RampFrom, "ramp-from", 0, "number of workers or target rate of the first ramp step")
+	cmd.Flags().Float64Var(&cfg.RampTo, "ramp-to", 0, "number of workers or target rate of the last ramp step")
+	cmd.Flags().IntVar(&cfg.RampSteps, "ramp-steps", defaultRampSteps, "number of ramp steps")
+	cmd.Flags().DurationVar(&cfg.StepDuration, "step-duration", defaultStepDuration, "duration of each ramp step")
+	cmd.Flags().DurationVar(&cfg.Warmup, "warmup", 0, "exclude queries started within this duration of the run starting from the stats (e.g. 30s)")
+	cmd.Flags().IntVar(&cfg.WarmupQueries, "warmup-queries", 0, "exclude the first N queries started from the stats")
+	cmd.Flags().DurationVar(&cfg.QueryTimeout, "query-timeout", 0, "cancel queries client-side that run longer than this (e.g. 30s)")
+	cmd.Flags().DurationVar(&cfg.StatementTimeout, "statement-timeout", 0, "set the postgres statement_timeout of each session (e.g. 30s)")
+	cmd.Flags().DurationVar(&cfg.DrainTimeout, "drain-timeout", defaultDrainTimeout, "time given to in-flight queries to complete when interrupted")
+
+	return cmd
+}
+
+// run creates a new worker pool and starts dispatching any received tasks to its workers in the background.
+// Rows are read from the specified CPU usage CSV file and transformed into queries that return the max and
+// min cpu of the host for every minute between the start end time. Each query is submitted as a task to the
+// worker pool task queue which are then picked up and executed by workers. A route key is used to ensure all
+// queries with a particular host name are executed on the same worker. Finally, wait occurs until all query
+// tasks have been completed. If the command context is cancelled (e.g. by SIGINT), reading stops, in-flight
+// queries are drained and a partial summary is output.
+func run(cmd *cobra.Command, args []string) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	database, err := db.Open(cfg.DatabaseConnection, cfg.StatementTimeout)
+	if err != nil {
+		return fmt.Errorf("error opening database connection: %w", err)
+	}
+
+	filepath := args[0]
+
+	var rawLog *rawlog.Writer
+	var observers []pool.Observer[queryResult]
+	if cfg.RawLog != "" {
+		if rawLog, err = rawlog.Create(cfg.RawLog); err != nil {
+			return fmt.Errorf("error creating raw log: %w", err)
+		}
+		defer rawLog.Close()
+		observers = append(observers, rawLogObserver(rawLog))
+	}
+
+	if cfg.Ramp != "" {
+		return runRamp(ctx, database, filepath, rawLog, observers)
+	}
+
+	verifier, err := newVerifier(ctx)
+	if err != nil {
+		return err
+	}
+	if verifier != nil {
+		// Close is deferred so that verification stops if the run fails, and is safe to call again.
+		defer verifier.Close()
+		observers = append(observers, verifyObserver(verifier))
+	}
+
+	result, runtime, err := runPool(ctx, cfg, database, filepath, "", observers...)
+	if err != nil {
+		return err
+	}
+
+	if err = closeRawLog(rawLog); err != nil {
+		return err
+	}
+
+	if result.Interrupted {
+		warningPrinter.Printf("Benchmark interrupted, %d queries skipped and results are partial\n", result.Skipped)
+	}
+
+	b := report.New(cfg, runtime, result)
+	if verifier != nil {
+		summary := verifier.Close()
+		b.Verification = &summary
+	}
+
+	if err = writeBenchmark(b); err != nil {
+		return fmt.Errorf("error rendering benchmark results: %w", err)
+	}
+
+	if cfg.TimelineFile != "" {
+		if err = writeTimeline(b); err != nil {
+			return fmt.Errorf("error writing timeline: %w", err)
+		}
+	}
+
+	if b.Verification != nil && b.Verification.Mismatched > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d queries returned unexpected results", b.Verification.Mismatched)
+	}
+
+	return nil
+}
+
+// runRamp runs a pool for each step of the configured load profile, with the number of workers or
+// target rate increasing each step, then outputs the stats of each step and the saturation point.
+func runRamp(ctx context.Context, database *sql.DB, filepath string, rawLog *rawlog.Writer, observers []pool.Observer[queryResult]) error {
+	loads := cfg.RampLoads()
+
+	var steps []report.RampStep
+	var interrupted bool
+
+	unit := "workers"
+	if cfg.Ramp == config.RampRate {
+		unit = "QPS"
+	}
+
+	for i, load := range loads {
+		label := fmt.Sprintf("Step %d/%d (%s %s)", i+1, len(loads), strconv.FormatFloat(load, 'f', -1, 64), unit)
+		result, runtime, err := runPool(ctx, cfg.Step(load), database, filepath, label, observers...)
+		if err != nil {
+			return err
+		}
+
+		steps = append(steps, report.NewRampStep(i+1, load, runtime, result))
+		zap.L().Debug("ramp step done", zap.Int("step", i+1), zap.Float64("load", load))
+
+		if result.Interrupted {
+			interrupted = true
+			break
+		}
+	}
+
+	if interrupted {
+		warningPrinter.Printf("Ramp interrupted after %d of %d steps\n", len(steps), len(loads))
+	}
+
+	if err := closeRawLog(rawLog); err != nil {
+		return err
+	}
+
+	return writeOutput(func(w io.Writer) error {
+		return report.RenderRamp(w, report.NewRamp(cfg, steps, interrupted), report.Format(cfg.OutputFormat))
+	})
+}
+
+// runPool creates a worker pool with the given config and executes the queries of the CSV file,
+// returning the pool result and runtime once all queries have completed or been skipped. The live
+// progress display is shown with the given label if enabled, and the result of each query is
+// delivered to the given observers.
+func runPool(ctx context.Context, c config.Config, database *sql.DB, filepath string, label string, observers ...pool.Observer[queryResult]) (*pool.Result, time.Duration, error) {
+	router, err := pool.NewRouter[queryResult](c.Routing, time.Now().UnixNano())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := time.Now()
+
+	queryPool := pool.New(pool.Config[queryResult]{
+		MaxWorkers:      c.MaxWorkers,
+		WorkerQueueSize: c.WorkerQueueSize,
+		WaitQueueSize:   c.WaitQueueSize,
+		Histogram: stats.HistogramConfig{
+			SignificantFigures: c.HistogramPrecision,
+			MaxValue:           c.HistogramMaxValue,
+		},
+		RouteKeyStats:    c.TopHosts > 0,
+		TimelineInterval: c.TimelineInterval,
+		LiveStats:        progressEnabled(),
+		DrainTimeout:     c.DrainTimeout,
+		WarmupDuration:   c.Warmup,
+		WarmupTasks:      c.WarmupQueries,
+		Router:           router,
+		WorkStealing:     c.WorkStealing,
+		IdleTimeout:      c.IdleTimeout,
+		MinWorkers:       c.MinWorkers,
+		IsTimeout:        db.IsTimeout,
+	})
+	for _, observer := range observers {
+		queryPool.Observe(observer)
+	}
+
+	poolCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queryPool.Dispatch(poolCtx)
+
+	// stop cancels the pool and waits for it before returning an error, so that no task or observer
+	// outlives the run, such as by writing to a raw log that has since been closed.
+	stop := func(err error) (*pool.Result, time.Duration, error) {
+		cancel()
+		queryPool.Wait()
+		return nil, 0, err
+	}
+
+	if progressEnabled() {
+		display, err := startProgress(queryPool, c, label)
+		if err != nil {
+			return stop(err)
+		}
+		defer display.Stop()
+	}
+
+	readCtx := ctx
+	if c.Duration > 0 {
+		var cancel context.CancelFunc
+		readCtx, cancel = context.WithTimeout(ctx, c.Duration)
+		defer cancel()
+	}
+
+	if err := readAndQueue(readCtx, c, filepath, database, queryPool); err != nil {
+		return stop(fmt.Errorf("error reading and queing queries: %w", err))
+	}
+
+	return queryPool.Wait(), time.Now().Sub(start), nil
+}
+
+// writeBenchmark renders the benchmark in the configured output format to stdout, or to the
+// output file if one was specified.
+func writeBenchmark(b report.Benchmark) error {
+	return writeOutput(func(w io.Writer) error {
+		return report.Render(w, b, report.Format(cfg.OutputFormat))
+	})
+}
+
+// writeOutput calls render with stdout, or with the output file if one was specified.
+func writeOutput(render func(w io.Writer) error) error {
+	if cfg.OutputFile == "" {
+		return render(os.Stdout)
+	}
+
+	file, err := os.Create(cfg.OutputFile)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %w", err)
+	}
+	defer file.Close()
+
+	pterm.DisableColor()
+	err = render(file)
+	pterm.EnableColor()
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printf("Benchmark results written to %s\n", cfg.OutputFile)
+	return nil
+}
+
+// readAndQueue submits a query task to the pool for each row of the CSV file, replaying the file for the
+// configured number of iterations and stopping early if ctx is cancelled. If a target rate is configured,
+// each task is submitted at its scheduled time, or immediately if the pool has fallen behind schedule.
+func readAndQueue(ctx context.Context, c config.Config, filepath string, database *sql.DB, queryPool *pool.Pool[queryResult]) error {
+	csvfile, err := os.Open(filepath)
+	if err != nil {
+		return fmt.Errorf("error opening csv file: %w", err)
+	}
+	defer csvfile.Close()
+
+	var schedule *rate.Schedule
+	if c.Rate > 0 {
+		if schedule, err = rate.NewSchedule(time.Now(), c.Rate, c.Arrival, time.Now().UnixNano()); err != nil {
+			return err
+		}
+	}
+
+	rowCh, errCh := csv.Repeat(ctx, csvfile, c.ReaderBufferSize, c.ReadIterations())
+
+	for {
+		select {
+		case row, ok := <-rowCh:
+			if !ok {
+				return nil
+			}
+			query := queryResult{Line: row.Line, Host: row.Fields[0], Start: row.Fields[1], End: row.Fields[2]}
+
+			task := &pool.Task[queryResult]{
+				RouteKey:         query.Host,
+				AffinityOptional: c.WorkStealing,
+				Func: func(ctx context.Context) (queryResult, error) {
+					if c.QueryTimeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, c.QueryTimeout)
+						defer cancel()
+					}
+					var err error
+					query.Results, err = usage.QueryMinMaxUsagePerMinuteInRange(ctx, database, query.Host, query.Start, query.End)
+					return query, err
+				},
+			}
+
+			if schedule != nil {
+				task.Scheduled = schedule.Next()
+				if !sleepUntil(ctx, task.Scheduled) {
+					return nil
+				}
+			}
+
+			queryPool.Submit(task)
+		case err = <-errCh:
+			return fmt.Errorf("error reading from csv file: %w", err)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// queryResult is the value of a query task, which identifies the CSV row the query was created from
+// alongside the rows it returned.
+type queryResult struct {
+	Line    int
+	Host    string
+	Start   string
+	End     string
+	Results []usage.Result
+}
+
+// rawLogObserver returns an observer that records each query to the raw log.
+func rawLogObserver(rawLog *rawlog.Writer) pool.Observer[queryResult] {
+	return pool.ObserverFunc[queryResult](func(result pool.TaskResult[queryResult]) {
+		rawLog.Write(newRawRecord(result))
+	})
+}
+
+// newRawRecord returns the raw log record of a completed query task.
+func newRawRecord(result pool.TaskResult[queryResult]) rawlog.Record {
+	record := rawlog.Record{
+		Line:     result.Value.Line,
+		Host:     result.Value.Host,
+		Start:    result.Value.Start,
+		End:      result.Value.End,
+		WorkerID: result.WorkerID,
+		Started:  result.Start,
+		Duration: result.End.Sub(result.Start),
+		Rows:     len(result.Value.Results),
+		Warmup:   result.Warmup,
+	}
+	if !result.Task.Scheduled.IsZero() {
+		scheduled := result.Task.Scheduled
+		record.Scheduled = &scheduled
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+	}
+	return record
+}
+
+// closeRawLog waits for the raw log to be written, if enabled.
+func closeRawLog(rawLog *rawlog.Writer) error {
+	if rawLog == nil {
+		return nil
+	}
+	if err := rawLog.Close(); err != nil {
+		return fmt.Errorf("error writing raw log: %w", err)
+	}
+	successPrinter.Printf("Raw log written to %s\n", cfg.RawLog)
+	return nil
+}
+
+// newVerifier returns a verifier of query results against the expected results file or reference
+// database, or nil if results are not verified.
+func newVerifier(ctx context.Context) (*verify.Verifier, error) {
+	var source verify.Source
+
+	switch {
+	case cfg.ExpectedResults != "":
+		expected, err := verify.LoadFile(cfg.ExpectedResults)
+		if err != nil {
+			return nil, fmt.Errorf("error loading expected results: %w", err)
+		}
+		source = expected
+	case cfg.ReferenceDatabase != "":
+		reference, err := db.Open(cfg.ReferenceDatabase, cfg.StatementTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("error opening reference database connection: %w", err)
+		}
+		source = verify.NewDatabaseSource(reference)
+	default:
+		return nil, nil
+	}
+
+	// Verify with as many reference queries in flight as workers, so verification keeps pace.
+	return verify.New(ctx, source, cfg.MaxWorkers), nil
+}
+
+// verifyObserver returns an observer that verifies the results of each successful query.
+func verifyObserver(verifier *verify.Verifier) pool.Observer[queryResult] {
+	return pool.ObserverFunc[queryResult](func(result pool.TaskResult[queryResult]) {
+		if result.Err != nil {
+			return
+		}
+		query := result.Value
+		verifier.Verify(query.Line, verify.Query{Host: query.Host, Start: query.Start, End: query.End}, query.Results)
+	})
+}
+
+// sleepUntil blocks until t, returning false if ctx is cancelled first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// writeTimeline exports the benchmark timeline to the timeline file as JSON if the file has a
+// .json extension, otherwise as CSV.
+func writeTimeline(b report.Benchmark) error {
+	format := report.FormatCSV
+	if filepath.Ext(cfg.TimelineFile) == ".json" {
+		format = report.FormatJSON
+	}
+
+	file, err := os.Create(cfg.TimelineFile)
+	if err != nil {
+		return fmt.Errorf("error creating timeline file: %w", err)
+	}
+	defer file.Close()
+
+	if err = report.RenderTimeline(file, b, format); err != nil {
+		return err
+	}
+
+	successPrinter.Printf("Timeline written to %s\n", cfg.TimelineFile)
+	return nil
+}
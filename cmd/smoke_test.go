@@ -19,6 +19,11 @@ func Test_run(t *testing.T) {
 	cfg.WorkerQueueSize = 1
 	cfg.ReaderBufferSize = 10
 	cfg.DatabaseConnection = dbConn
+	cfg.HistogramPrecision = defaultHistogramPrecision
+	cfg.HistogramMaxValue = defaultHistogramMax
+	cfg.OutputFormat = string(defaultOutputFormat)
+	cfg.Arrival = defaultArrival
+	cfg.Routing = defaultRouting
 
 	cmd := &cobra.Command{
 		RunE: run,
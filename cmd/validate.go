@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/params"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func newValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate csv_file",
+		Short: "Validate a query params CSV file",
+		Long: "validate checks that a query params CSV file has the expected header and that every row " +
+			"has a hostname and a valid start and end time, reporting every invalid row",
+		RunE: validate,
+		Args: cobra.ExactArgs(1),
+	}
+}
+
+func validate(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("error opening csv file: %w", err)
+	}
+	defer file.Close()
+
+	v, err := params.Validate(file)
+	if err != nil {
+		return fmt.Errorf("error reading csv file: %w", err)
+	}
+
+	for _, rowErr := range v.Errors {
+		pterm.Error.Println(rowErr.Error())
+	}
+
+	if len(v.Errors) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d invalid rows found in %s", len(v.Errors), args[0])
+	}
+
+	pterm.Success.Printf("%s is valid: %d rows across %d hosts\n", args[0], v.Rows, v.Hosts)
+	return nil
+}
@@ -1,17 +1,95 @@
 package config
 
-import "github.com/go-ozzo/ozzo-validation"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-ozzo/ozzo-validation"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
 
+const redacted = "xxxxx"
+
+var (
+	passwordPattern = regexp.MustCompile(`password=\S+`)
+	rawLogPattern   = regexp.MustCompile(`\.(csv|jsonl)$`)
+)
+
+// Config holds the settings of a benchmark run. The flag tag of each field is the name of its
+// command line flag, which is also used as its config file key and environment variable name.
 type Config struct {
-	MaxWorkers         int
-	WorkerQueueSize    int
-	WaitQueueSize      int
-	ReaderBufferSize   int
-	Debug              bool
-	DatabaseConnection string
+	MaxWorkers         int           `flag:"max-workers"`
+	WorkerQueueSize    int           `flag:"worker-size"`
+	WaitQueueSize      int           `flag:"wait-size"`
+	ReaderBufferSize   int           `flag:"reader-size"`
+	Debug              bool          `flag:"debug"`
+	DatabaseConnection string        `flag:"dbconn"`
+	Percentiles        []float64     `flag:"percentiles"`
+	HistogramPrecision int           `flag:"histogram-precision"`
+	HistogramMaxValue  time.Duration `flag:"histogram-max"`
+	OutputFormat       string        `flag:"output-format"`
+	OutputFile         string        `flag:"output-file"`
+	PerWorker          bool          `flag:"per-worker"`
+	TopHosts           int           `flag:"top-hosts"`
+	TimelineInterval   time.Duration `flag:"timeline-interval"`
+	TimelineFile       string        `flag:"timeline-file"`
+	NoProgress         bool          `flag:"no-progress"`
+	DrainTimeout       time.Duration `flag:"drain-timeout"`
+	QueryTimeout       time.Duration `flag:"query-timeout"`
+	StatementTimeout   time.Duration `flag:"statement-timeout"`
+	Duration           time.Duration `flag:"duration"`
+	Iterations         int           `flag:"iterations"`
+	Rate               float64       `flag:"rate"`
+	Arrival            string        `flag:"arrival"`
+	Ramp               string        `flag:"ramp"`
+	RampFrom           float64       `flag:"ramp-from"`
+	RampTo             float64       `flag:"ramp-to"`
+	RampSteps          int           `flag:"ramp-steps"`
+	StepDuration       time.Duration `flag:"step-duration"`
+	Warmup             time.Duration `flag:"warmup"`
+	WarmupQueries      int           `flag:"warmup-queries"`
+	Routing            string        `flag:"routing"`
+	WorkStealing       bool          `flag:"work-stealing"`
+	IdleTimeout        time.Duration `flag:"idle-timeout"`
+	MinWorkers         int           `flag:"min-workers"`
+	RawLog             string        `flag:"raw-log"`
+	ExpectedResults    string        `flag:"expected-results"`
+	ReferenceDatabase  string        `flag:"reference-dbconn"`
+
+	// sources maps flag names to where their value was loaded from, which is only populated by Load.
+	sources map[string]Source
 }
 
+// Load profiles that ramp a value in steps.
+const (
+	RampWorkers = "workers"
+	RampRate    = "rate"
+)
+
+// Validate checks that all config values are valid. If the config was populated by Load, each
+// error includes where the invalid value was loaded from.
 func (c Config) Validate() error {
+	err := c.validate()
+
+	errs, ok := err.(validation.Errors)
+	if !ok || c.sources == nil {
+		return err
+	}
+
+	for field, fieldErr := range errs {
+		if source, ok := c.sources[flagName(field)]; ok {
+			errs[field] = fmt.Errorf("%v (%s)", fieldErr, source)
+		}
+	}
+	return errs
+}
+
+func (c Config) validate() error {
 	return validation.ValidateStruct(&c,
 
 		validation.Field(&c.MaxWorkers, validation.Required, validation.Min(1)),
@@ -19,5 +97,194 @@ func (c Config) Validate() error {
 		validation.Field(&c.WaitQueueSize, validation.Required, validation.Min(1)),
 		validation.Field(&c.ReaderBufferSize, validation.Required, validation.Min(1)),
 		validation.Field(&c.DatabaseConnection, validation.Required, validation.Required),
+		validation.Field(&c.HistogramPrecision, validation.Required, validation.Min(1), validation.Max(5)),
+		validation.Field(&c.HistogramMaxValue, validation.Required, validation.Min(time.Millisecond)),
+		validation.Field(&c.OutputFormat, validation.Required, validation.In("text", "json", "csv", "markdown")),
+		validation.Field(&c.Routing, validation.Required, validation.In("sticky", "hash", "round-robin", "least-loaded", "random")),
+		validation.Field(&c.TopHosts, validation.Min(0)),
+		validation.Field(&c.TimelineInterval, requiredIf(c.TimelineFile != ""), validation.Min(time.Duration(0))),
+		validation.Field(&c.DrainTimeout, validation.Min(time.Duration(0))),
+		validation.Field(&c.QueryTimeout, validation.Min(time.Duration(0))),
+		validation.Field(&c.Duration, validation.Min(time.Duration(0))),
+		validation.Field(&c.Iterations, validation.Min(0)),
+		validation.Field(&c.Rate, validation.Min(0.0)),
+		validation.Field(&c.Arrival, requiredIf(c.Rate > 0 || c.Ramp == RampRate), validation.In("constant", "poisson")),
+		validation.Field(&c.Ramp, validation.In(RampWorkers, RampRate), validation.By(c.rampRouting)),
+		validation.Field(&c.RampFrom, requiredIf(c.Ramp != ""), validation.Min(0.0).Exclusive()),
+		validation.Field(&c.RampTo, requiredIf(c.Ramp != ""), validation.Min(c.RampFrom).Exclusive()),
+		validation.Field(&c.RampSteps, requiredIf(c.Ramp != ""), validation.Min(2)),
+		validation.Field(&c.StepDuration, requiredIf(c.Ramp != ""), validation.Min(time.Duration(0))),
+		validation.Field(&c.Warmup, validation.Min(time.Duration(0))),
+		validation.Field(&c.WarmupQueries, validation.Min(0)),
+		validation.Field(&c.IdleTimeout, validation.Min(time.Duration(0)), validation.By(c.stickyRouting)),
+		validation.Field(&c.MinWorkers, validation.Min(0), validation.Max(c.MaxWorkers)),
+		validation.Field(&c.RawLog, validation.Match(rawLogPattern).Error("must be a .csv or .jsonl file")),
+		validation.Field(&c.ExpectedResults, validation.Match(rawLogPattern).Error("must be a .csv or .jsonl file"),
+			validation.By(c.noRamp)),
+		validation.Field(&c.ReferenceDatabase, validation.By(c.noRamp), validation.By(c.noExpectedResults)),
+		// statement_timeout has millisecond precision
+		validation.Field(&c.StatementTimeout, validation.Min(time.Millisecond)),
+		validation.Field(&c.Percentiles, validation.Each(validation.Required, validation.Min(0.0).Exclusive(), validation.Max(100.0))),
 	)
 }
+
+// stickyRouting is a rule that fails if a value is set without sticky routing, as workers are only
+// started on demand with sticky routing.
+func (c Config) stickyRouting(value interface{}) error {
+	if !validation.IsEmpty(value) && c.Routing != "sticky" {
+		return errors.New("only supported with sticky routing")
+	}
+	return nil
+}
+
+// rampRouting is a rule that fails if workers are ramped with sticky routing, which only starts a
+// worker per host, so a ramp beyond the number of hosts would not add any workers.
+func (c Config) rampRouting(value interface{}) error {
+	if value == RampWorkers && c.Routing == "sticky" {
+		return errors.New("workers ramp not supported with sticky routing, as workers are only started per host")
+	}
+	return nil
+}
+
+// noRamp is a rule that fails if a value is set with a ramp, as results are only verified for a
+// single run.
+func (c Config) noRamp(value interface{}) error {
+	if !validation.IsEmpty(value) && c.Ramp != "" {
+		return errors.New("not supported with a ramp")
+	}
+	return nil
+}
+
+// noExpectedResults is a rule that fails if a value is set along with an expected results file, as
+// results are verified against either a file or a reference database.
+func (c Config) noExpectedResults(value interface{}) error {
+	if !validation.IsEmpty(value) && c.ExpectedResults != "" {
+		return errors.New("cannot be used with an expected results file")
+	}
+	return nil
+}
+
+// requiredIf returns the required rule if the condition is true, otherwise a rule that always passes.
+func requiredIf(condition bool) validation.Rule {
+	if condition {
+		return validation.Required
+	}
+	return validation.By(func(interface{}) error { return nil })
+}
+
+// Redacted returns a copy of the config with any password removed from the database connection
+// strings, so that it is safe to include in benchmark output.
+func (c Config) Redacted() Config {
+	c.DatabaseConnection = redactConn(c.DatabaseConnection)
+	c.ReferenceDatabase = redactConn(c.ReferenceDatabase)
+	return c
+}
+
+// redactConn returns the connection string with any password replaced.
+func redactConn(conn string) string {
+	if u, err := url.Parse(conn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+		return conn
+	}
+	return passwordPattern.ReplaceAllString(conn, "password="+redacted)
+}
+
+// ReadIterations returns the number of times the query file should be read, where zero means
+// indefinitely. It defaults to once, unless the run is bounded by a duration instead.
+func (c Config) ReadIterations() int {
+	if c.Iterations == 0 && c.Duration == 0 {
+		return 1
+	}
+	return c.Iterations
+}
+
+// RampLoads returns the load of each step of a ramp, linearly spaced from RampFrom to RampTo.
+// Worker counts are rounded to whole workers.
+func (c Config) RampLoads() []float64 {
+	if c.Ramp == "" || c.RampSteps < 2 {
+		return nil
+	}
+
+	loads := make([]float64, c.RampSteps)
+	for i := range loads {
+		loads[i] = c.RampFrom + float64(i)*(c.RampTo-c.RampFrom)/float64(c.RampSteps-1)
+		if c.Ramp == RampWorkers {
+			loads[i] = math.Round(loads[i])
+		}
+	}
+	return loads
+}
+
+// Step returns the config of a single step of a ramp with the given load. Each step replays the
+// query file until the step duration elapses.
+func (c Config) Step(load float64) Config {
+	switch c.Ramp {
+	case RampWorkers:
+		c.MaxWorkers = int(load)
+	case RampRate:
+		c.Rate = load
+	}
+	c.Duration = c.StepDuration
+	c.Iterations = 0
+	return c
+}
+
+// flagName returns the flag tag of the Config field with the given name.
+func flagName(field string) string {
+	f, ok := reflect.TypeOf(Config{}).FieldByName(field)
+	if !ok {
+		return ""
+	}
+	return f.Tag.Get("flag")
+}
+
+// MarshalJSON encodes the config with a snake_case key derived from the flag name of each field,
+// so that the encoding does not depend on field names. Durations are encoded as nanoseconds with
+// an _ns suffix, like the rest of the JSON output.
+func (c Config) MarshalJSON() ([]byte, error) {
+	values := make(map[string]interface{})
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		if key := jsonKey(v.Type().Field(i)); key != "" {
+			values[key] = v.Field(i).Interface()
+		}
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON decodes a config encoded by MarshalJSON.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := jsonKey(v.Type().Field(i))
+		value, ok := values[key]
+		if key == "" || !ok {
+			continue
+		}
+		if err := json.Unmarshal(value, v.Field(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("error decoding config %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// jsonKey returns the JSON key of a Config field, which is empty if the field has no flag.
+func jsonKey(f reflect.StructField) string {
+	name := f.Tag.Get("flag")
+	if name == "" {
+		return ""
+	}
+	key := strings.ReplaceAll(name, "-", "_")
+	if f.Type == reflect.TypeOf(time.Duration(0)) {
+		key += "_ns"
+	}
+	return key
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables that override config values. For example,
+// TSBENCH_MAX_WORKERS overrides the max-workers flag.
+const EnvPrefix = "TSBENCH_"
+
+// Source describes where a config value was loaded from.
+type Source string
+
+func sourceFlag(name string) Source {
+	return Source("from flag --" + name)
+}
+
+func sourceEnv(name string) Source {
+	return Source("from env " + name)
+}
+
+func sourceFile(path string) Source {
+	return Source("from file " + path)
+}
+
+const sourceDefault Source = "from default"
+
+// EnvName returns the environment variable that overrides the given flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Load sets any Config flags that were not provided on the command line from environment variables,
+// then from the config file at path, leaving the remaining flags at their defaults. This gives
+// a precedence of flags > env > file > defaults. The config file may be YAML or TOML depending
+// on its extension, and its keys are flag names. Where each Config value was loaded from is
+// recorded so that Validate can report the source of invalid values.
+func (c *Config) Load(flags *pflag.FlagSet, path string) error {
+	file := make(map[string]interface{})
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return err
+		}
+	}
+
+	names := flagNames()
+
+	var unknown []string
+	for key := range file {
+		if !names[key] || flags.Lookup(key) == nil {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	c.sources = make(map[string]Source)
+
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || !names[flag.Name] {
+			return
+		}
+
+		source := sourceDefault
+		if flag.Changed {
+			source = sourceFlag(flag.Name)
+		} else if value, ok := os.LookupEnv(EnvName(flag.Name)); ok {
+			source = sourceEnv(EnvName(flag.Name))
+			if setErr := flag.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, EnvName(flag.Name), setErr)
+			}
+		} else if value, ok := file[flag.Name]; ok {
+			source = sourceFile(path)
+			if setErr := flag.Value.Set(formatValue(value)); setErr != nil {
+				err = fmt.Errorf("invalid value %v for %s in config file %s: %w", value, flag.Name, path, setErr)
+			}
+		}
+
+		c.sources[flag.Name] = source
+	})
+
+	return err
+}
+
+// flagNames returns the set of flag names that map to Config fields.
+func flagNames() map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("flag"); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, expected .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// formatValue formats a decoded config file value in the form expected by pflag.Value.Set.
+// Lists are joined with commas, as accepted by slice flags.
+func formatValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
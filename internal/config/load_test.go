@@ -0,0 +1,195 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fromTestFile is replaced with the source of the config file written by the test.
+const fromTestFile Source = "<file>"
+
+func newTestFlags(c *Config) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.IntVar(&c.MaxWorkers, "max-workers", 10, "")
+	flags.IntVar(&c.WorkerQueueSize, "worker-size", 50, "")
+	flags.StringVar(&c.DatabaseConnection, "dbconn", "host=localhost", "")
+	flags.Float64SliceVar(&c.Percentiles, "percentiles", []float64{50, 99}, "")
+	flags.DurationVar(&c.TimelineInterval, "timeline-interval", 0, "")
+	flags.BoolVar(&c.PerWorker, "per-worker", false, "")
+	flags.String("config", "", "")
+	return flags
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfig_Load(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		env      map[string]string
+		args     []string
+		want     Config
+		wantFrom map[string]Source
+	}{
+		{
+			name: "defaults",
+			want: Config{
+				MaxWorkers:         10,
+				WorkerQueueSize:    50,
+				DatabaseConnection: "host=localhost",
+				Percentiles:        []float64{50, 99},
+			},
+			wantFrom: map[string]Source{"max-workers": sourceDefault, "dbconn": sourceDefault},
+		},
+		{
+			name: "yaml file",
+			file: "bench.yaml",
+			content: "max-workers: 20\n" +
+				"percentiles: [90, 99.9]\n" +
+				"timeline-interval: 1s\n" +
+				"per-worker: true\n",
+			want: Config{
+				MaxWorkers:         20,
+				WorkerQueueSize:    50,
+				DatabaseConnection: "host=localhost",
+				Percentiles:        []float64{90, 99.9},
+				TimelineInterval:   time.Second,
+				PerWorker:          true,
+			},
+			wantFrom: map[string]Source{"max-workers": fromTestFile, "worker-size": sourceDefault},
+		},
+		{
+			name: "toml file",
+			file: "bench.toml",
+			content: "max-workers = 20\n" +
+				"percentiles = [90, 99.9]\n" +
+				"dbconn = \"host=timescaledb\"\n",
+			want: Config{
+				MaxWorkers:         20,
+				WorkerQueueSize:    50,
+				DatabaseConnection: "host=timescaledb",
+				Percentiles:        []float64{90, 99.9},
+			},
+			wantFrom: map[string]Source{"dbconn": fromTestFile},
+		},
+		{
+			name:    "flags over env over file",
+			file:    "bench.yaml",
+			content: "max-workers: 20\nworker-size: 20\ndbconn: host=file\n",
+			env:     map[string]string{"TSBENCH_MAX_WORKERS": "30", "TSBENCH_WORKER_SIZE": "30"},
+			args:    []string{"--max-workers", "40"},
+			want: Config{
+				MaxWorkers:         40,
+				WorkerQueueSize:    30,
+				DatabaseConnection: "host=file",
+				Percentiles:        []float64{50, 99},
+			},
+			wantFrom: map[string]Source{
+				"max-workers": "from flag --max-workers",
+				"worker-size": "from env TSBENCH_WORKER_SIZE",
+				"dbconn":      fromTestFile,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var path string
+			if tt.file != "" {
+				path = writeTestFile(t, tt.file, tt.content)
+			}
+
+			var got Config
+			flags := newTestFlags(&got)
+			require.NoError(t, flags.Parse(tt.args))
+			require.NoError(t, got.Load(flags, path))
+
+			for flag, source := range tt.wantFrom {
+				if source == fromTestFile {
+					source = sourceFile(path)
+				}
+				assert.Equal(t, source, got.sources[flag], flag)
+			}
+			got.sources = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_Load_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "unknown key",
+			file:    "bench.yaml",
+			content: "max-workers: 20\nworkers: 20\nconfig: other.yaml\n",
+			wantErr: "unknown keys in config file",
+		},
+		{
+			name:    "unsupported extension",
+			file:    "bench.json",
+			content: "{}",
+			wantErr: "unsupported config file extension \".json\"",
+		},
+		{
+			name:    "invalid file value",
+			file:    "bench.toml",
+			content: "max-workers = \"many\"\n",
+			wantErr: "invalid value many for max-workers in config file",
+		},
+		{
+			name:    "invalid env value",
+			env:     map[string]string{"TSBENCH_MAX_WORKERS": "many"},
+			wantErr: "invalid value \"many\" for TSBENCH_MAX_WORKERS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var path string
+			if tt.file != "" {
+				path = writeTestFile(t, tt.file, tt.content)
+			}
+
+			var c Config
+			err := c.Load(newTestFlags(&c), path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestConfig_Validate_source(t *testing.T) {
+	t.Setenv("TSBENCH_MAX_WORKERS", "-1")
+
+	var c Config
+	flags := newTestFlags(&c)
+	require.NoError(t, flags.Parse([]string{"--worker-size", "0"}))
+	require.NoError(t, c.Load(flags, ""))
+
+	err := c.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MaxWorkers: must be no less than 1 (from env TSBENCH_MAX_WORKERS)")
+	assert.Contains(t, err.Error(), "WorkerQueueSize: cannot be blank (from flag --worker-size)")
+}
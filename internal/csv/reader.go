@@ -1,39 +1,88 @@
 package csv
 
 import (
+	"context"
 	"encoding/csv"
 	"go.uber.org/zap"
 	"io"
 )
 
-// Read reads rows from the provided CSV file and sends results to a channel for consumption.
-func Read(file io.Reader, bufferSize int) (chan []string, chan error) {
-	rowCh := make(chan []string, bufferSize)
+// Row is a single row of a CSV file.
+type Row struct {
+	// Line is the line number of the row in the file, starting at 1 for the header row.
+	Line   int
+	Fields []string
+}
+
+// Repeat reads rows from the provided CSV file and sends them to a channel for consumption,
+// rewinding the file and streaming its rows again until it has been read the given number of
+// iterations, or indefinitely if iterations is zero. The row channel is closed once all iterations are complete, when ctx is cancelled, or
+// if the file has no rows.
+func Repeat(ctx context.Context, file io.ReadSeeker, bufferSize int, iterations int) (chan Row, chan error) {
+	rowCh := make(chan Row, bufferSize)
 	errCh := make(chan error)
 
 	go func() {
-		reader := csv.NewReader(file)
+		defer close(rowCh)
 
-		// Read header row
-		if _, err := reader.Read(); err != nil {
-			errCh <- err
-		}
-
-		for {
-			row, err := reader.Read()
-			if err != nil {
-				if err == io.EOF {
-					close(rowCh)
-					zap.L().Debug("finished reading csv file")
+		for i := 0; iterations == 0 || i < iterations; i++ {
+			if i > 0 {
+				if _, err := file.Seek(0, io.SeekStart); err != nil {
+					sendErr(ctx, errCh, err)
 					return
 				}
-				errCh <- err
-				close(errCh)
+			}
 
+			rows, err := readRows(ctx, file, rowCh)
+			if err != nil {
+				if ctx.Err() == nil {
+					sendErr(ctx, errCh, err)
+				}
+				return
 			}
-			rowCh <- row
+			if rows == 0 {
+				return
+			}
+			zap.L().Debug("finished reading csv file", zap.Int("iteration", i+1))
 		}
 	}()
 
 	return rowCh, errCh
 }
+
+// readRows reads a single pass over a CSV file, skipping the header row, and returns the number
+// of rows sent to the row channel.
+func readRows(ctx context.Context, file io.Reader, rowCh chan<- Row) (int, error) {
+	reader := csv.NewReader(file)
+
+	// Read header row
+	if _, err := reader.Read(); err != nil {
+		return 0, err
+	}
+
+	var rows int
+	for {
+		fields, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				return rows, nil
+			}
+			return rows, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		select {
+		case rowCh <- Row{Line: line, Fields: fields}:
+			rows++
+		case <-ctx.Done():
+			return rows, ctx.Err()
+		}
+	}
+}
+
+func sendErr(ctx context.Context, errCh chan<- error, err error) {
+	select {
+	case errCh <- err:
+	case <-ctx.Done():
+	}
+}
@@ -1,50 +1,97 @@
 package csv
 
 import (
+	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"os"
+	"strings"
 	"testing"
 )
 
-func TestRead(t *testing.T) {
+type errFile struct {
+	wantErr error
+}
+
+func (e errFile) Read(_ []byte) (int, error) {
+	return 0, e.wantErr
+}
+
+func (e errFile) Seek(_ int64, _ int) (int64, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestRepeat(t *testing.T) {
+	tests := []struct {
+		name       string
+		iterations int
+		wantRows   int
+	}{
+		{
+			name:       "1 iteration",
+			iterations: 1,
+			wantRows:   5,
+		},
+		{
+			name:       "3 iterations",
+			iterations: 3,
+			wantRows:   15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvfile, err := os.Open("testdata/valid.csv")
+			require.NoError(t, err)
+			defer csvfile.Close()
+
+			rowsCh, errCh := Repeat(context.Background(), csvfile, 1, tt.iterations)
+
+			var rows int
+			for row := range rowsCh {
+				assert.Len(t, row.Fields, 3)
+				assert.Equal(t, rows%5+2, row.Line)
+				rows++
+			}
+			assert.Equal(t, tt.wantRows, rows)
+			assert.Empty(t, errCh)
+		})
+	}
+}
+
+func TestRepeat_untilCancelled(t *testing.T) {
 	csvfile, err := os.Open("testdata/valid.csv")
 	require.NoError(t, err)
 	defer csvfile.Close()
 
-	rowsCh, errCh := Read(csvfile, 1)
-
-	wantRows := 5
-	wantColumns := 3
+	ctx, cancel := context.WithCancel(context.Background())
+	rowsCh, _ := Repeat(ctx, csvfile, 1, 0)
 
-	for i := 0; i < wantRows; i++ {
+	for i := 0; i < 100; i++ {
 		row := <-rowsCh
-		assert.Len(t, row, wantColumns)
+		assert.Len(t, row.Fields, 3)
+	}
+	cancel()
+
+	for range rowsCh {
 	}
+}
+
+func TestRepeat_noRows(t *testing.T) {
+	rowsCh, errCh := Repeat(context.Background(), strings.NewReader("hostname,start_time,end_time\n"), 1, 0)
 
 	_, open := <-rowsCh
 	assert.False(t, open)
 	assert.Empty(t, errCh)
 }
 
-func TestRead_error(t *testing.T) {
+func TestRepeat_error(t *testing.T) {
 	wantErr := errors.New("some error")
 
-	file := errFile{
-		wantErr: wantErr,
-	}
-
-	rowsCh, errCh := Read(file, 1)
+	rowsCh, errCh := Repeat(context.Background(), errFile{wantErr: wantErr}, 1, 2)
 	err := <-errCh
 	assert.EqualError(t, err, wantErr.Error())
 	assert.Empty(t, rowsCh)
 }
-
-type errFile struct {
-	wantErr error
-}
-
-func (e errFile) Read(_ []byte) (int, error) {
-	return 0, e.wantErr
-}
@@ -1,20 +1,41 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	_ "github.com/jackc/pgx/v4"
-	_ "github.com/jackc/pgx/v4/stdlib"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
 	"go.uber.org/zap"
+	"strconv"
 	"time"
 )
 
-// Open opens a postgres database for the provided connection details.
-func Open(conn string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", conn)
+// queryCanceledCode is the postgres error code returned when a statement is cancelled, which
+// includes when it exceeds the statement_timeout and when the client requests a cancel, such as
+// when in-flight queries are cancelled on shutdown.
+const queryCanceledCode = "57014"
+
+// statementTimeoutMessage is the message of a queryCanceledCode error caused by the
+// statement_timeout.
+const statementTimeoutMessage = "canceling statement due to statement timeout"
+
+// Open opens a postgres database for the provided connection details. If statementTimeout is
+// greater than zero, it is set as the statement_timeout of every session so that the server
+// aborts any longer running queries.
+func Open(conn string, statementTimeout time.Duration) (*sql.DB, error) {
+	config, err := pgx.ParseConfig(conn)
 	if err != nil {
 		return nil, err
 	}
 
+	if statementTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(statementTimeout.Milliseconds(), 10)
+	}
+
+	db := stdlib.OpenDB(*config)
+
 	if err = checkHealth(db); err != nil {
 		return nil, err
 	}
@@ -22,6 +43,17 @@ func Open(conn string) (*sql.DB, error) {
 	return db, nil
 }
 
+// IsTimeout reports whether a query error was caused by a client-side context deadline or by
+// the server-side statement_timeout. Queries cancelled at the client's request are not timeouts.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == queryCanceledCode && pgErr.Message == statementTimeoutMessage
+}
+
 func checkHealth(db *sql.DB) error {
 	retries := 10
 	interval := time.Second
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "context deadline exceeded",
+			err:  fmt.Errorf("timeout: %w", context.DeadlineExceeded),
+			want: true,
+		},
+		{
+			name: "statement timeout",
+			err:  &pgconn.PgError{Code: queryCanceledCode, Message: "canceling statement due to statement timeout"},
+			want: true,
+		},
+		{
+			name: "cancelled by user request",
+			err:  &pgconn.PgError{Code: queryCanceledCode, Message: "canceling statement due to user request"},
+			want: false,
+		},
+		{
+			name: "context canceled",
+			err:  context.Canceled,
+			want: false,
+		},
+		{
+			name: "other postgres error",
+			err:  &pgconn.PgError{Code: "42P01", Message: "relation does not exist"},
+			want: false,
+		},
+		{
+			name: "other error",
+			err:  errors.New("some error"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTimeout(tt.err))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package params
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/go-ozzo/ozzo-validation"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// GenerateConfig configures the generation of a query params file.
+type GenerateConfig struct {
+	Rows   int
+	Hosts  int
+	From   time.Time
+	To     time.Time
+	Window time.Duration
+	Seed   int64
+}
+
+func (c GenerateConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Rows, validation.Required, validation.Min(1)),
+		validation.Field(&c.Hosts, validation.Required, validation.Min(1), validation.Max(1000000)),
+		validation.Field(&c.From, validation.Required),
+		validation.Field(&c.To, validation.Required, validation.Min(c.From)),
+		validation.Field(&c.Window, validation.Required, validation.Min(time.Second)),
+	)
+}
+
+// Generate writes a query params file with rows for randomly selected hosts in the form
+// host_000000. Each row queries a window of the configured size that starts at a random time
+// between from and to. The same seed always generates the same file.
+func Generate(w io.Writer, config GenerateConfig) error {
+	rng := rand.New(rand.NewSource(config.Seed))
+	spanSeconds := int64(config.To.Sub(config.From) / time.Second)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Header); err != nil {
+		return err
+	}
+
+	for i := 0; i < config.Rows; i++ {
+		start := config.From
+		if spanSeconds > 0 {
+			start = start.Add(time.Duration(rng.Int63n(spanSeconds)) * time.Second)
+		}
+
+		p := QueryParams{
+			Host:  fmt.Sprintf("host_%06d", rng.Intn(config.Hosts)),
+			Start: start,
+			End:   start.Add(config.Window),
+		}
+		if err := writer.Write(p.Row()); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
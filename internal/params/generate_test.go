@@ -0,0 +1,46 @@
+package params
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	config := GenerateConfig{
+		Rows:   100,
+		Hosts:  5,
+		From:   time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC),
+		Window: time.Hour,
+		Seed:   1,
+	}
+	require.NoError(t, config.Validate())
+
+	var buf bytes.Buffer
+	require.NoError(t, Generate(&buf, config))
+
+	v, err := Validate(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 100, v.Rows)
+	assert.LessOrEqual(t, v.Hosts, 5)
+	assert.Empty(t, v.Errors)
+
+	var again bytes.Buffer
+	require.NoError(t, Generate(&again, config))
+	assert.Equal(t, buf.String(), again.String())
+}
+
+func TestGenerateConfig_Validate(t *testing.T) {
+	err := GenerateConfig{
+		From: time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+	}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Rows: cannot be blank")
+	assert.Contains(t, err.Error(), "Hosts: cannot be blank")
+	assert.Contains(t, err.Error(), "To: must be no less than")
+	assert.Contains(t, err.Error(), "Window: cannot be blank")
+}
@@ -0,0 +1,52 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimestampLayout is the layout of the start and end timestamps in a query params file.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// Header is the header row of a query params file.
+var Header = []string{"hostname", "start_time", "end_time"}
+
+// QueryParams are the parameters of a single CPU usage query.
+type QueryParams struct {
+	Host  string
+	Start time.Time
+	End   time.Time
+}
+
+// Parse parses a query params file row in the form hostname,start_time,end_time.
+func Parse(row []string) (QueryParams, error) {
+	if len(row) != len(Header) {
+		return QueryParams{}, fmt.Errorf("expected %d columns, got %d", len(Header), len(row))
+	}
+
+	if row[0] == "" {
+		return QueryParams{}, errors.New("hostname cannot be blank")
+	}
+
+	start, err := time.Parse(TimestampLayout, row[1])
+	if err != nil {
+		return QueryParams{}, fmt.Errorf("invalid start_time %q: must have layout %s", row[1], TimestampLayout)
+	}
+
+	end, err := time.Parse(TimestampLayout, row[2])
+	if err != nil {
+		return QueryParams{}, fmt.Errorf("invalid end_time %q: must have layout %s", row[2], TimestampLayout)
+	}
+
+	if end.Before(start) {
+		return QueryParams{}, errors.New("end_time must not be before start_time")
+	}
+
+	return QueryParams{Host: row[0], Start: start, End: end}, nil
+}
+
+// Row formats the query params as a query params file row.
+func (p QueryParams) Row() []string {
+	return []string{p.Host, p.Start.Format(TimestampLayout), p.End.Format(TimestampLayout)}
+}
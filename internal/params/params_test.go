@@ -0,0 +1,58 @@
+package params
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]string{"host_000008", "2017-01-01 08:59:22", "2017-01-01 09:59:22"})
+	require.NoError(t, err)
+	assert.Equal(t, "host_000008", p.Host)
+	assert.Equal(t, time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC), p.Start)
+	assert.Equal(t, time.Date(2017, 1, 1, 9, 59, 22, 0, time.UTC), p.End)
+	assert.Equal(t, []string{"host_000008", "2017-01-01 08:59:22", "2017-01-01 09:59:22"}, p.Row())
+}
+
+func TestParse_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     []string
+		wantErr string
+	}{
+		{
+			name:    "wrong column count",
+			row:     []string{"host_000008", "2017-01-01 08:59:22"},
+			wantErr: "expected 3 columns, got 2",
+		},
+		{
+			name:    "blank hostname",
+			row:     []string{"", "2017-01-01 08:59:22", "2017-01-01 09:59:22"},
+			wantErr: "hostname cannot be blank",
+		},
+		{
+			name:    "invalid start time",
+			row:     []string{"host_000008", "2017-01-01T08:59:22", "2017-01-01 09:59:22"},
+			wantErr: `invalid start_time "2017-01-01T08:59:22": must have layout 2006-01-02 15:04:05`,
+		},
+		{
+			name:    "invalid end time",
+			row:     []string{"host_000008", "2017-01-01 08:59:22", "tomorrow"},
+			wantErr: `invalid end_time "tomorrow": must have layout 2006-01-02 15:04:05`,
+		},
+		{
+			name:    "end before start",
+			row:     []string{"host_000008", "2017-01-01 09:59:22", "2017-01-01 08:59:22"},
+			wantErr: "end_time must not be before start_time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.row)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package params
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RowError is a validation error for a single row of a query params file.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Validation is the result of validating a query params file.
+type Validation struct {
+	Rows   int
+	Hosts  int
+	Errors []RowError
+}
+
+// Validate checks that the header and every row of a query params file are valid. Row errors
+// are collected rather than returned so that all invalid rows can be reported at once. An error
+// is only returned if the file cannot be read as CSV.
+func Validate(r io.Reader) (Validation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var v Validation
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		v.Errors = append(v.Errors, RowError{Line: 1, Err: fmt.Errorf("missing header")})
+		return v, nil
+	} else if err != nil {
+		return v, err
+	}
+
+	if strings.Join(header, ",") != strings.Join(Header, ",") {
+		v.Errors = append(v.Errors, RowError{
+			Line: 1,
+			Err:  fmt.Errorf("expected header %q, got %q", strings.Join(Header, ","), strings.Join(header, ",")),
+		})
+	}
+
+	hosts := make(map[string]bool)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return v, err
+		}
+
+		v.Rows++
+		line, _ := reader.FieldPos(0)
+
+		p, err := Parse(row)
+		if err != nil {
+			v.Errors = append(v.Errors, RowError{Line: line, Err: err})
+			continue
+		}
+		hosts[p.Host] = true
+	}
+
+	v.Hosts = len(hosts)
+	return v, nil
+}
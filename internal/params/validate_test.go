@@ -0,0 +1,44 @@
+package params
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	file, err := os.Open("../../database/query_params.csv")
+	require.NoError(t, err)
+	defer file.Close()
+
+	v, err := Validate(file)
+	require.NoError(t, err)
+	assert.Equal(t, 200, v.Rows)
+	assert.Equal(t, 10, v.Hosts)
+	assert.Empty(t, v.Errors)
+}
+
+func TestValidate_invalidRows(t *testing.T) {
+	input := "host,start,end\n" +
+		"host_000001,2017-01-01 08:59:22,2017-01-01 09:59:22\n" +
+		"host_000002,2017-01-01 08:59:22\n" +
+		"host_000003,2017-01-01 09:59:22,2017-01-01 08:59:22\n"
+
+	v, err := Validate(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 3, v.Rows)
+	assert.Equal(t, 1, v.Hosts)
+	require.Len(t, v.Errors, 3)
+	assert.EqualError(t, v.Errors[0], `line 1: expected header "hostname,start_time,end_time", got "host,start,end"`)
+	assert.EqualError(t, v.Errors[1], "line 3: expected 3 columns, got 2")
+	assert.EqualError(t, v.Errors[2], "line 4: end_time must not be before start_time")
+}
+
+func TestValidate_empty(t *testing.T) {
+	v, err := Validate(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Len(t, v.Errors, 1)
+	assert.EqualError(t, v.Errors[0], "line 1: missing header")
+}
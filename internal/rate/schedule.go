@@ -0,0 +1,58 @@
+package rate
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Arrival distributions.
+const (
+	Constant = "constant"
+	Poisson  = "poisson"
+)
+
+// Schedule generates the intended start times of tasks arriving at a target rate, independent of
+// how long previous tasks take to complete. A Schedule is not safe for concurrent use.
+type Schedule struct {
+	next     time.Time
+	interval time.Duration
+	random   *rand.Rand
+}
+
+// NewSchedule creates a schedule of arrivals starting at start with a mean of qps arrivals per
+// second. Constant arrivals are evenly spaced, while Poisson arrivals have exponentially
+// distributed intervals generated from the given seed.
+func NewSchedule(start time.Time, qps float64, arrival string, seed int64) (*Schedule, error) {
+	if qps <= 0 {
+		return nil, fmt.Errorf("rate must be greater than 0, got %v", qps)
+	}
+
+	s := &Schedule{
+		next:     start,
+		interval: time.Duration(float64(time.Second) / qps),
+	}
+
+	switch arrival {
+	case Constant:
+	case Poisson:
+		s.random = rand.New(rand.NewSource(seed))
+	default:
+		return nil, fmt.Errorf("unknown arrival distribution: %s", arrival)
+	}
+
+	return s, nil
+}
+
+// Next returns the intended start time of the next arrival.
+func (s *Schedule) Next() time.Time {
+	next := s.next
+
+	interval := s.interval
+	if s.random != nil {
+		interval = time.Duration(s.random.ExpFloat64() * float64(s.interval))
+	}
+	s.next = s.next.Add(interval)
+
+	return next
+}
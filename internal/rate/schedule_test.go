@@ -0,0 +1,62 @@
+package rate
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSchedule_constant(t *testing.T) {
+	start := time.Now()
+	schedule, err := NewSchedule(start, 100, Constant, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, start.Add(time.Duration(i)*10*time.Millisecond), schedule.Next())
+	}
+}
+
+func TestSchedule_poisson(t *testing.T) {
+	start := time.Now()
+	schedule, err := NewSchedule(start, 1000, Poisson, 1)
+	require.NoError(t, err)
+
+	arrivals := 10000
+	prev := schedule.Next()
+	assert.Equal(t, start, prev)
+
+	var varied bool
+	for i := 1; i < arrivals; i++ {
+		next := schedule.Next()
+		assert.False(t, next.Before(prev))
+		if next.Sub(prev) != time.Millisecond {
+			varied = true
+		}
+		prev = next
+	}
+
+	assert.True(t, varied)
+	// The mean interval should be close to 1ms
+	assert.InEpsilon(t, float64(arrivals)*float64(time.Millisecond), float64(prev.Sub(start)), 0.05)
+}
+
+func TestSchedule_reproducible(t *testing.T) {
+	start := time.Now()
+	a, err := NewSchedule(start, 50, Poisson, 42)
+	require.NoError(t, err)
+	b, err := NewSchedule(start, 50, Poisson, 42)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, a.Next(), b.Next())
+	}
+}
+
+func TestNewSchedule_errors(t *testing.T) {
+	_, err := NewSchedule(time.Now(), 0, Constant, 0)
+	assert.EqualError(t, err, "rate must be greater than 0, got 0")
+
+	_, err = NewSchedule(time.Now(), 10, "bursty", 0)
+	assert.EqualError(t, err, "unknown arrival distribution: bursty")
+}
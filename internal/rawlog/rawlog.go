@@ -0,0 +1,292 @@
+// Package rawlog records every query execution of a benchmark to a CSV or JSONL file, for analysis
+// beyond the summary stats such as plotting latency over time or finding the slowest queries.
+package rawlog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// bufferSize is the number of records that can be queued before Write blocks.
+const bufferSize = 10000
+
+// Record is a single query execution.
+type Record struct {
+	// Line is the line number of the query in the CSV file.
+	Line  int    `json:"line"`
+	Host  string `json:"hostname"`
+	Start string `json:"start_time"`
+	End   string `json:"end_time"`
+
+	WorkerID int `json:"worker_id"`
+	// Scheduled is when the query was scheduled to start, or nil without a target rate.
+	Scheduled *time.Time    `json:"scheduled,omitempty"`
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"duration_ns"`
+	Rows      int           `json:"rows"`
+	Warmup    bool          `json:"warmup"`
+	Error     string        `json:"error,omitempty"`
+}
+
+var csvHeader = []string{"line", "hostname", "start_time", "end_time", "worker_id", "scheduled", "started",
+	"duration_ns", "rows", "warmup", "error"}
+
+func (r Record) csv() []string {
+	var scheduled string
+	if r.Scheduled != nil {
+		scheduled = r.Scheduled.Format(time.RFC3339Nano)
+	}
+	return []string{
+		strconv.Itoa(r.Line),
+		r.Host,
+		r.Start,
+		r.End,
+		strconv.Itoa(r.WorkerID),
+		scheduled,
+		r.Started.Format(time.RFC3339Nano),
+		strconv.FormatInt(int64(r.Duration), 10),
+		strconv.Itoa(r.Rows),
+		strconv.FormatBool(r.Warmup),
+		r.Error,
+	}
+}
+
+// Writer writes records in the background, so that recording a query does not delay the worker
+// that executed it. Records are never dropped, so Write only blocks if the writer has fallen
+// behind by more than its buffer.
+type Writer struct {
+	records   chan Record
+	done      chan struct{}
+	closer    io.Closer
+	closeOnce sync.Once
+	err       error
+}
+
+// Create creates the file at path and returns a Writer that writes records to it as JSONL if the
+// file has a .jsonl extension, otherwise as CSV.
+func Create(path string) (*Writer, error) {
+	format := FormatCSV
+	if filepath.Ext(path) == ".jsonl" {
+		format = FormatJSONL
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWriter(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.closer = file
+	return w, nil
+}
+
+// NewWriter returns a Writer that writes records to w in the given format.
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	buf := bufio.NewWriter(w)
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(buf)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return start(func(r Record) error {
+			return cw.Write(r.csv())
+		}, func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return buf.Flush()
+		}), nil
+	case FormatJSONL:
+		enc := json.NewEncoder(buf)
+		return start(func(r Record) error {
+			return enc.Encode(r)
+		}, buf.Flush), nil
+	default:
+		return nil, fmt.Errorf("unsupported raw log format: %s", format)
+	}
+}
+
+// start returns a Writer that encodes each record in a background goroutine, then flushes once
+// the writer is closed. Records are discarded after the first error, which is returned by Close.
+func start(encode func(Record) error, flush func() error) *Writer {
+	w := &Writer{
+		records: make(chan Record, bufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+
+		var written int
+		for record := range w.records {
+			if w.err != nil {
+				continue
+			}
+			if w.err = encode(record); w.err == nil {
+				written++
+			}
+		}
+		if w.err == nil {
+			w.err = flush()
+		}
+		zap.L().Debug("finished writing raw log", zap.Int("records", written))
+	}()
+
+	return w
+}
+
+// Write queues a record to be written. It must not be called after Close.
+func (w *Writer) Write(record Record) {
+	w.records <- record
+}
+
+// Close waits for all queued records to be written, then flushes and closes the underlying file
+// if the writer was created with Create. It is safe to call Close more than once.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.records)
+		<-w.done
+		if w.closer != nil {
+			if err := w.closer.Close(); err != nil && w.err == nil {
+				w.err = err
+			}
+		}
+	})
+	return w.err
+}
+
+// ReadFile reads all records from the raw log at path, which is parsed as JSONL if the file has a
+// .jsonl extension, otherwise as CSV.
+func ReadFile(path string) ([]Record, error) {
+	format := FormatCSV
+	if filepath.Ext(path) == ".jsonl" {
+		format = FormatJSONL
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file, format)
+}
+
+// Read reads all records from r in the given format.
+func Read(r io.Reader, format Format) ([]Record, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSONL:
+		return readJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported raw log format: %s", format)
+	}
+}
+
+func readJSONL(r io.Reader) ([]Record, error) {
+	var records []Record
+	dec := json.NewDecoder(r)
+	for {
+		var record Record
+		if err := dec.Decode(&record); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+
+	if _, err := reader.Read(); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			return nil, err
+		}
+
+		record, err := parseCSV(row)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+func parseCSV(row []string) (Record, error) {
+	record := Record{
+		Host:  row[1],
+		Start: row[2],
+		End:   row[3],
+		Error: row[10],
+	}
+
+	var err error
+	if record.Line, err = strconv.Atoi(row[0]); err != nil {
+		return Record{}, err
+	}
+	if record.WorkerID, err = strconv.Atoi(row[4]); err != nil {
+		return Record{}, err
+	}
+	if row[5] != "" {
+		scheduled, err := time.Parse(time.RFC3339Nano, row[5])
+		if err != nil {
+			return Record{}, err
+		}
+		record.Scheduled = &scheduled
+	}
+	if record.Started, err = time.Parse(time.RFC3339Nano, row[6]); err != nil {
+		return Record{}, err
+	}
+	duration, err := strconv.ParseInt(row[7], 10, 64)
+	if err != nil {
+		return Record{}, err
+	}
+	record.Duration = time.Duration(duration)
+	if record.Rows, err = strconv.Atoi(row[8]); err != nil {
+		return Record{}, err
+	}
+	if record.Warmup, err = strconv.ParseBool(row[9]); err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
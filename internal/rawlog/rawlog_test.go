@@ -0,0 +1,200 @@
+package rawlog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRecords() []Record {
+	started := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	scheduled := started.Add(-time.Millisecond)
+	return []Record{
+		{
+			Line:     2,
+			Host:     "host_000008",
+			Start:    "2017-01-01 08:59:22",
+			End:      "2017-01-01 09:59:22",
+			WorkerID: 1,
+			Started:  started,
+			Duration: 5 * time.Millisecond,
+			Rows:     60,
+			Warmup:   true,
+		},
+		{
+			Line:      3,
+			Host:      "host_000001",
+			Start:     "2017-01-02 13:02:02",
+			End:       "2017-01-02 14:02:02",
+			WorkerID:  2,
+			Scheduled: &scheduled,
+			Started:   started,
+			Duration:  time.Second,
+			Error:     "context deadline exceeded",
+		},
+	}
+}
+
+func TestWriter_csv(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatCSV)
+	require.NoError(t, err)
+
+	for _, record := range newTestRecords() {
+		w.Write(record)
+	}
+	require.NoError(t, w.Close())
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, csvHeader, rows[0])
+	assert.Equal(t, []string{"2", "host_000008", "2017-01-01 08:59:22", "2017-01-01 09:59:22", "1", "",
+		"2022-01-01T12:00:00Z", "5000000", "60", "true", ""}, rows[1])
+	assert.Equal(t, []string{"3", "host_000001", "2017-01-02 13:02:02", "2017-01-02 14:02:02", "2",
+		"2022-01-01T11:59:59.999Z", "2022-01-01T12:00:00Z", "1000000000", "0", "false", "context deadline exceeded"}, rows[2])
+}
+
+func TestWriter_jsonl(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatJSONL)
+	require.NoError(t, err)
+
+	want := newTestRecords()
+	for _, record := range want {
+		w.Write(record)
+	}
+	require.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, len(want))
+	assert.NotContains(t, lines[0], "scheduled")
+	assert.NotContains(t, lines[0], "error")
+
+	for i, line := range lines {
+		var got Record
+		require.NoError(t, json.Unmarshal([]byte(line), &got))
+		assert.Equal(t, want[i].Line, got.Line)
+		assert.Equal(t, want[i].Duration, got.Duration)
+		assert.Equal(t, want[i].Error, got.Error)
+		assert.True(t, want[i].Started.Equal(got.Started))
+	}
+}
+
+func TestWriter_error(t *testing.T) {
+	wantErr := errors.New("some error")
+
+	w, err := NewWriter(errWriter{wantErr: wantErr}, FormatJSONL)
+	require.NoError(t, err)
+
+	for i := 0; i < 10000; i++ {
+		w.Write(Record{Line: i})
+	}
+	assert.EqualError(t, w.Close(), wantErr.Error())
+	assert.EqualError(t, w.Close(), wantErr.Error(), "close should be idempotent")
+}
+
+type errWriter struct {
+	wantErr error
+}
+
+func (e errWriter) Write(_ []byte) (int, error) {
+	return 0, e.wantErr
+}
+
+func TestNewWriter_unsupportedFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xml")
+	assert.EqualError(t, err, "unsupported raw log format: xml")
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		wantFirst string
+	}{
+		{
+			name:      "csv",
+			file:      "queries.csv",
+			wantFirst: "line,hostname,",
+		},
+		{
+			name:      "jsonl",
+			file:      "queries.jsonl",
+			wantFirst: `{"line":2,`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			w, err := Create(path)
+			require.NoError(t, err)
+
+			w.Write(newTestRecords()[0])
+			require.NoError(t, w.Close())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(data), tt.wantFirst), string(data))
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			require.NoError(t, err)
+
+			want := newTestRecords()
+			for _, record := range want {
+				w.Write(record)
+			}
+			require.NoError(t, w.Close())
+
+			got, err := Read(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, got, len(want))
+			for i := range want {
+				assert.True(t, want[i].Started.Equal(got[i].Started))
+				got[i].Started = want[i].Started
+				if want[i].Scheduled != nil {
+					require.NotNil(t, got[i].Scheduled)
+					assert.True(t, want[i].Scheduled.Equal(*got[i].Scheduled))
+					got[i].Scheduled = want[i].Scheduled
+				}
+				assert.Equal(t, want[i], got[i])
+			}
+		})
+	}
+}
+
+func TestRead_invalidCSV(t *testing.T) {
+	data := strings.Join(csvHeader, ",") + "\n" + "2,host_000008,a,b,1,,2022-01-01T12:00:00Z,5000000,many,false,\n"
+
+	_, err := Read(strings.NewReader(data), FormatCSV)
+	assert.EqualError(t, err, `line 2: strconv.Atoi: parsing "many": invalid syntax`)
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.jsonl")
+	w, err := Create(path)
+	require.NoError(t, err)
+	w.Write(newTestRecords()[0])
+	require.NoError(t, w.Close())
+
+	records, err := ReadFile(path)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 60, records[0].Rows)
+}
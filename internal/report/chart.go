@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 60
+	chartHeight = 8
+)
+
+// asciiChart renders values as a vertical bar chart with a y-axis labelled from zero to the max
+// value, and an x-axis labelled from zero to end. Values are downsampled by averaging so that the
+// chart is at most width columns wide.
+func asciiChart(title string, values []float64, end time.Duration, width, height int) string {
+	values = downsample(values, width)
+
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+
+	maxLabel := fmt.Sprintf("%.1f", max)
+	labelWidth := len(maxLabel)
+
+	var sb strings.Builder
+	sb.WriteString(title + "\n")
+
+	for row := height; row >= 1; row-- {
+		label := ""
+		if row == height {
+			label = maxLabel
+		} else if row == 1 {
+			label = "0.0"
+		}
+		fmt.Fprintf(&sb, "%*s ┤", labelWidth, label)
+
+		for _, v := range values {
+			if max > 0 && v/max*float64(height) >= float64(row)-0.5 {
+				sb.WriteString("█")
+			} else {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "%*s └%s\n", labelWidth, "", strings.Repeat("─", len(values)))
+
+	endLabel := end.String()
+	padding := len(values) - len("0s") - len(endLabel)
+	if padding < 1 {
+		padding = 1
+	}
+	fmt.Fprintf(&sb, "%*s  0s%s%s\n", labelWidth, "", strings.Repeat(" ", padding), endLabel)
+
+	return sb.String()
+}
+
+// downsample averages consecutive values so that at most width values remain.
+func downsample(values []float64, width int) []float64 {
+	if len(values) <= width {
+		return values
+	}
+
+	size := int(math.Ceil(float64(len(values)) / float64(width)))
+	var sampled []float64
+	for i := 0; i < len(values); i += size {
+		j := i + size
+		if j > len(values) {
+			j = len(values)
+		}
+
+		var sum float64
+		for _, v := range values[i:j] {
+			sum += v
+		}
+		sampled = append(sampled, sum/float64(j-i))
+	}
+
+	return sampled
+}
@@ -0,0 +1,24 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_asciiChart(t *testing.T) {
+	got := asciiChart("QPS", []float64{0, 1, 2, 4}, 4*time.Second, 10, 4)
+	want := "QPS\n" +
+		"4.0 ┤   █\n" +
+		"    ┤   █\n" +
+		"    ┤  ██\n" +
+		"0.0 ┤ ███\n" +
+		"    └────\n" +
+		"     0s 4s\n"
+	assert.Equal(t, want, got)
+}
+
+func Test_downsample(t *testing.T) {
+	assert.Equal(t, []float64{1, 2}, downsample([]float64{1, 2}, 5))
+	assert.Equal(t, []float64{1.5, 3.5, 5}, downsample([]float64{1, 2, 3, 4, 5}, 3))
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Comparison is the result of comparing a candidate benchmark against a baseline.
+type Comparison struct {
+	Deltas   []Delta  `json:"deltas"`
+	Breaches []string `json:"breaches"`
+}
+
+// Delta is the difference in a single metric between a baseline and a candidate benchmark.
+// DeltaPercent is nil when the baseline is zero. Threshold is the max allowed regression as a
+// percentage, and is nil when no threshold was configured for the metric.
+type Delta struct {
+	Metric       string   `json:"metric"`
+	Duration     bool     `json:"duration"`
+	Baseline     float64  `json:"baseline"`
+	Candidate    float64  `json:"candidate"`
+	Delta        float64  `json:"delta"`
+	DeltaPercent *float64 `json:"delta_percent"`
+	Threshold    *float64 `json:"threshold"`
+	Regressed    bool     `json:"regressed"`
+	Breached     bool     `json:"breached"`
+}
+
+type comparedMetric struct {
+	metric string
+	// higherIsWorse is true for metrics such as query time, and false for metrics such as
+	// throughput where a decrease is a regression.
+	higherIsWorse bool
+	duration      bool
+	value         func(b Benchmark) (float64, bool)
+}
+
+func durationValue(f func(b Benchmark) time.Duration) func(b Benchmark) (float64, bool) {
+	return func(b Benchmark) (float64, bool) {
+		return float64(f(b)), true
+	}
+}
+
+func intValue(f func(b Benchmark) int) func(b Benchmark) (float64, bool) {
+	return func(b Benchmark) (float64, bool) {
+		return float64(f(b)), true
+	}
+}
+
+func percentileValue(p float64) func(b Benchmark) (float64, bool) {
+	return func(b Benchmark) (float64, bool) {
+		for _, pct := range b.Latency.Percentiles {
+			if pct.P == p {
+				return float64(pct.Value), true
+			}
+		}
+		return 0, false
+	}
+}
+
+// comparedMetrics returns the metrics that can be compared between the two benchmarks. Percentiles
+// are only included if they were reported by both benchmarks.
+func comparedMetrics(baseline, candidate Benchmark) []comparedMetric {
+	metrics := []comparedMetric{
+		{metric: "runtime", higherIsWorse: true, duration: true, value: durationValue(func(b Benchmark) time.Duration { return b.Runtime })},
+		{metric: "query_processing_time", higherIsWorse: true, duration: true, value: durationValue(func(b Benchmark) time.Duration { return b.QueryProcessingTime })},
+		{metric: "query_executions", higherIsWorse: false, value: intValue(func(b Benchmark) int { return b.QueryExecutions })},
+		{metric: "query_errors", higherIsWorse: true, value: intValue(func(b Benchmark) int { return b.QueryErrors })},
+		{metric: "query_timeouts", higherIsWorse: true, value: intValue(func(b Benchmark) int { return b.QueryTimeouts })},
+		{metric: "qps", higherIsWorse: false, value: func(b Benchmark) (float64, bool) {
+			runtime := b.measuredRuntime()
+			if runtime <= 0 {
+				return 0, true
+			}
+			return float64(b.QueryExecutions) / runtime.Seconds(), true
+		}},
+		{metric: "min", higherIsWorse: true, duration: true, value: durationValue(func(b Benchmark) time.Duration { return b.Latency.Min })},
+		{metric: "max", higherIsWorse: true, duration: true, value: durationValue(func(b Benchmark) time.Duration { return b.Latency.Max })},
+		{metric: "median", higherIsWorse: true, duration: true, value: durationValue(func(b Benchmark) time.Duration { return b.Latency.Median })},
+		{metric: "avg", higherIsWorse: true, duration: true, value: durationValue(func(b Benchmark) time.Duration { return b.Latency.Avg })},
+	}
+
+	for _, pct := range baseline.Latency.Percentiles {
+		if _, ok := percentileValue(pct.P)(candidate); ok {
+			metrics = append(metrics, comparedMetric{
+				metric:        "p" + formatPercentile(pct.P),
+				higherIsWorse: true,
+				duration:      true,
+				value:         percentileValue(pct.P),
+			})
+		}
+	}
+
+	return metrics
+}
+
+// Compare compares a candidate benchmark against a baseline. Thresholds map metric names to the
+// max allowed regression as a percentage. A regression greater than the threshold is a breach,
+// as is any regression of a metric that was zero in the baseline.
+func Compare(baseline, candidate Benchmark, thresholds map[string]float64) (Comparison, error) {
+	var c Comparison
+
+	known := make(map[string]bool)
+	for _, m := range comparedMetrics(baseline, candidate) {
+		known[m.metric] = true
+
+		base, _ := m.value(baseline)
+		cand, _ := m.value(candidate)
+
+		d := Delta{
+			Metric:    m.metric,
+			Duration:  m.duration,
+			Baseline:  base,
+			Candidate: cand,
+			Delta:     cand - base,
+		}
+
+		if base != 0 {
+			pct := d.Delta / math.Abs(base) * 100
+			d.DeltaPercent = &pct
+		}
+
+		d.Regressed = (m.higherIsWorse && d.Delta > 0) || (!m.higherIsWorse && d.Delta < 0)
+
+		if threshold, ok := thresholds[m.metric]; ok {
+			threshold := threshold
+			d.Threshold = &threshold
+			if d.Regressed && (d.DeltaPercent == nil || math.Abs(*d.DeltaPercent) > threshold) {
+				d.Breached = true
+				c.Breaches = append(c.Breaches, m.metric)
+			}
+		}
+
+		c.Deltas = append(c.Deltas, d)
+	}
+
+	var unknown []string
+	for metric := range thresholds {
+		if !known[metric] {
+			unknown = append(unknown, metric)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return Comparison{}, fmt.Errorf("unknown threshold metrics: %s", strings.Join(unknown, ", "))
+	}
+
+	return c, nil
+}
+
+// ParseThresholds parses thresholds in the form metric=percent, e.g. p99=10 or p99=10%.
+func ParseThresholds(values []string) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	for _, value := range values {
+		metric, pct, ok := strings.Cut(value, "=")
+		if !ok || metric == "" {
+			return nil, fmt.Errorf("invalid threshold %q, expected metric=percent", value)
+		}
+
+		threshold, err := strconv.ParseFloat(strings.TrimSuffix(pct, "%"), 64)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid threshold %q, percent must be a non-negative number", value)
+		}
+
+		thresholds[metric] = threshold
+	}
+	return thresholds, nil
+}
+
+// Load reads a benchmark previously written in the JSON output format.
+func Load(path string) (Benchmark, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Benchmark{}, err
+	}
+
+	var b Benchmark
+	if err = json.Unmarshal(data, &b); err != nil {
+		return Benchmark{}, fmt.Errorf("error decoding benchmark %s: %w", path, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func findDelta(t *testing.T, c Comparison, metric string) Delta {
+	for _, d := range c.Deltas {
+		if d.Metric == metric {
+			return d
+		}
+	}
+	t.Fatalf("metric %s not found", metric)
+	return Delta{}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := newTestBenchmark()
+	candidate := newTestBenchmark()
+	candidate.Latency.Percentiles = []Percentile{{P: 99.9, Value: 180 * time.Millisecond}}
+	candidate.Latency.Median = 11 * time.Millisecond
+	candidate.QueryExecutions = 100
+	candidate.QueryErrors = 1
+
+	c, err := Compare(baseline, candidate, map[string]float64{
+		"p99.9":            10,
+		"median":           5,
+		"query_executions": 40,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"query_executions", "p99.9"}, c.Breaches)
+
+	p999 := findDelta(t, c, "p99.9")
+	assert.Equal(t, float64(150*time.Millisecond), p999.Baseline)
+	assert.Equal(t, float64(30*time.Millisecond), p999.Delta)
+	assert.InDelta(t, 20, *p999.DeltaPercent, 0.001)
+	assert.True(t, p999.Regressed)
+	assert.True(t, p999.Breached)
+
+	median := findDelta(t, c, "median")
+	assert.False(t, median.Regressed)
+	assert.False(t, median.Breached)
+
+	executions := findDelta(t, c, "query_executions")
+	assert.InDelta(t, -50, *executions.DeltaPercent, 0.001)
+	assert.True(t, executions.Regressed)
+
+	errs := findDelta(t, c, "query_errors")
+	assert.False(t, errs.Regressed)
+	assert.Nil(t, errs.Threshold)
+}
+
+func TestCompare_zeroBaseline(t *testing.T) {
+	baseline := newTestBenchmark()
+	baseline.QueryErrors = 0
+	candidate := newTestBenchmark()
+	candidate.QueryErrors = 3
+
+	c, err := Compare(baseline, candidate, map[string]float64{"query_errors": 0})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"query_errors"}, c.Breaches)
+	assert.Nil(t, findDelta(t, c, "query_errors").DeltaPercent)
+}
+
+func TestCompare_unknownThreshold(t *testing.T) {
+	_, err := Compare(newTestBenchmark(), newTestBenchmark(), map[string]float64{"p42": 1, "foo": 1})
+	assert.EqualError(t, err, "unknown threshold metrics: foo, p42")
+}
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds([]string{"p99=10", "avg=2.5%"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"p99": 10, "avg": 2.5}, got)
+
+	for _, value := range []string{"p99", "=10", "p99=abc", "p99=-1"} {
+		_, err = ParseThresholds([]string{value})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	want := newTestBenchmark()
+	path := filepath.Join(t.TempDir(), "results.json")
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, Render(file, want, FormatJSON))
+	require.NoError(t, file.Close())
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestRenderComparison(t *testing.T) {
+	candidate := newTestBenchmark()
+	candidate.Latency.Percentiles = []Percentile{{P: 99.9, Value: 180 * time.Millisecond}}
+
+	c, err := Compare(newTestBenchmark(), candidate, map[string]float64{"p99.9": 10})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderComparison(&buf, c, FormatMarkdown))
+	assert.Contains(t, buf.String(), "| Metric | Baseline | Candidate | Delta | Delta % | Threshold | Status |\n")
+	assert.Contains(t, buf.String(), "| p99.9 | 150ms | 180ms | +30ms | +20.00% | 10% | breached |\n")
+	assert.Contains(t, buf.String(), "| query_executions | 200 | 200 | 0 | +0.00% | - | ok |\n")
+}
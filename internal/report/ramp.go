@@ -0,0 +1,108 @@
+package report
+
+import (
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"time"
+)
+
+const (
+	// kneeScalingEfficiency is the min fraction of the load increase between two steps that
+	// throughput must increase by for the load to still be considered scaling.
+	kneeScalingEfficiency = 0.5
+	// kneeLatencyGrowth is the max ratio that p99 query time may grow by between two steps for
+	// the load to still be considered scaling.
+	kneeLatencyGrowth = 2.0
+)
+
+// Ramp is the summary of a stepped load profile, where each step runs with an increasing number
+// of workers or target rate. Durations are encoded as nanoseconds in JSON.
+type Ramp struct {
+	Config config.Config `json:"config"`
+	Mode   string        `json:"mode"`
+	Steps  []RampStep    `json:"steps"`
+	// Knee is the number of the last step that throughput scaled up to, after which throughput
+	// stopped scaling with load or latency climbed. It is zero if throughput scaled across all
+	// steps.
+	Knee        int  `json:"knee,omitempty"`
+	Interrupted bool `json:"interrupted"`
+}
+
+// RampStep holds the stats of a single step of a ramp. Load is the number of workers or target
+// rate of the step, whereas WorkersStarted is the number of workers the step actually started.
+type RampStep struct {
+	Step            int           `json:"step"`
+	Load            float64       `json:"load"`
+	WorkersStarted  int           `json:"workers_started"`
+	Runtime         time.Duration `json:"runtime_ns"`
+	QueryExecutions int           `json:"query_executions"`
+	QueryErrors     int           `json:"query_errors"`
+	QPS             float64       `json:"qps"`
+	P50             time.Duration `json:"p50_ns"`
+	P99             time.Duration `json:"p99_ns"`
+	Max             time.Duration `json:"max_ns"`
+}
+
+// NewRampStep summarises the results of a single step of a ramp. Each step has its own warmup
+// phase, which is excluded from the runtime that QPS is measured over.
+func NewRampStep(step int, load float64, runtime time.Duration, result *pool.Result) RampStep {
+	s := RampStep{
+		Step:           step,
+		Load:           load,
+		WorkersStarted: len(result.Workers),
+		Runtime:        runtime,
+		P50:            result.Latencies.Percentile(50),
+		P99:            result.Latencies.Percentile(99),
+		Max:            result.Latencies.Max(),
+	}
+
+	for _, workerResult := range result.Workers {
+		s.QueryExecutions += workerResult.Completed
+		s.QueryErrors += workerResult.Errors
+	}
+
+	measured := runtime
+	if result.Warmup != nil {
+		measured -= result.Warmup.Elapsed
+	}
+	if measured > 0 {
+		s.QPS = float64(s.QueryExecutions) / measured.Seconds()
+	}
+
+	return s
+}
+
+// NewRamp creates the summary of a ramp from its steps and finds its knee. Any password in the
+// config database connection is redacted.
+func NewRamp(cfg config.Config, steps []RampStep, interrupted bool) Ramp {
+	return Ramp{
+		Config:      cfg.Redacted(),
+		Mode:        cfg.Ramp,
+		Steps:       steps,
+		Knee:        findKnee(steps),
+		Interrupted: interrupted,
+	}
+}
+
+// findKnee returns the number of the step after which throughput stops scaling with load, which
+// is when the throughput increase of the next step is less than half of its load increase, or
+// when its p99 query time more than doubles.
+func findKnee(steps []RampStep) int {
+	for i := 1; i < len(steps); i++ {
+		prev, step := steps[i-1], steps[i]
+		if prev.Load <= 0 || prev.QPS <= 0 {
+			continue
+		}
+
+		loadGain := step.Load/prev.Load - 1
+		qpsGain := step.QPS/prev.QPS - 1
+		if qpsGain < loadGain*kneeScalingEfficiency {
+			return prev.Step
+		}
+
+		if prev.P99 > 0 && float64(step.P99) > float64(prev.P99)*kneeLatencyGrowth {
+			return prev.Step
+		}
+	}
+	return 0
+}
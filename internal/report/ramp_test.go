@@ -0,0 +1,112 @@
+package report
+
+import (
+	"bytes"
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewRampStep(t *testing.T) {
+	result := newTestResult(
+		[]time.Duration{100 * time.Microsecond, 200 * time.Microsecond},
+		[]time.Duration{50 * time.Microsecond},
+	)
+	result.Workers[0].Errors = 1
+
+	step := NewRampStep(2, 10, 2*time.Second, result)
+	assert.Equal(t, 2, step.Step)
+	assert.Equal(t, 10.0, step.Load)
+	assert.Equal(t, 2, step.WorkersStarted)
+	assert.Equal(t, 3, step.QueryExecutions)
+	assert.Equal(t, 1, step.QueryErrors)
+	assert.Equal(t, 1.5, step.QPS)
+	assert.Equal(t, 100*time.Microsecond, step.P50)
+	assert.InEpsilon(t, 198*time.Microsecond, step.P99, 0.01)
+	assert.Equal(t, 200*time.Microsecond, step.Max)
+}
+
+func TestNewRampStep_warmup(t *testing.T) {
+	result := newTestResult([]time.Duration{100 * time.Microsecond, 200 * time.Microsecond})
+	result.Warmup = &pool.WarmupResult{Completed: 5, Elapsed: time.Second}
+
+	step := NewRampStep(1, 10, 2*time.Second, result)
+	assert.Equal(t, 2, step.QueryExecutions)
+	assert.Equal(t, 2.0, step.QPS, "warmup phase should be excluded from the runtime")
+	assert.Equal(t, 2*time.Second, step.Runtime)
+}
+
+func TestNewRamp_knee(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []RampStep
+		wantKnee int
+	}{
+		{
+			name: "throughput scales",
+			steps: []RampStep{
+				{Step: 1, Load: 10, QPS: 100, P99: time.Millisecond},
+				{Step: 2, Load: 20, QPS: 190, P99: time.Millisecond},
+				{Step: 3, Load: 40, QPS: 350, P99: time.Millisecond},
+			},
+			wantKnee: 0,
+		},
+		{
+			name: "throughput stops scaling",
+			steps: []RampStep{
+				{Step: 1, Load: 10, QPS: 100, P99: time.Millisecond},
+				{Step: 2, Load: 20, QPS: 190, P99: time.Millisecond},
+				{Step: 3, Load: 40, QPS: 220, P99: time.Millisecond},
+				{Step: 4, Load: 80, QPS: 225, P99: time.Millisecond},
+			},
+			wantKnee: 2,
+		},
+		{
+			name: "latency climbs",
+			steps: []RampStep{
+				{Step: 1, Load: 100, QPS: 100, P99: time.Millisecond},
+				{Step: 2, Load: 200, QPS: 200, P99: 5 * time.Millisecond},
+			},
+			wantKnee: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRamp(config.Config{Ramp: config.RampWorkers}, tt.steps, false)
+			assert.Equal(t, tt.wantKnee, r.Knee)
+		})
+	}
+}
+
+func TestRenderRamp(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	r := NewRamp(config.Config{Ramp: config.RampRate}, []RampStep{
+		{Step: 1, Load: 100, QPS: 100, P99: time.Millisecond},
+		{Step: 2, Load: 200, WorkersStarted: 8, QPS: 110, P99: time.Millisecond},
+	}, false)
+
+	var text bytes.Buffer
+	require.NoError(t, RenderRamp(&text, r, FormatText))
+	assert.Contains(t, text.String(), "Target QPS")
+	assert.Contains(t, text.String(), "Saturation point: step 1 (100 target qps) at 100.00 QPS")
+
+	var csv bytes.Buffer
+	require.NoError(t, RenderRamp(&csv, r, FormatCSV))
+	assert.Contains(t, csv.String(), "Step,Target QPS,Workers started,QPS,P50,P99,Max,Queries,Errors\n")
+	assert.Contains(t, csv.String(), "2,200,8,110.00,0s,1ms,0s,0,0\n")
+
+	var markdown bytes.Buffer
+	require.NoError(t, RenderRamp(&markdown, r, FormatMarkdown))
+	assert.Contains(t, markdown.String(), "## Ramp")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, RenderRamp(&jsonBuf, r, FormatJSON))
+	assert.Contains(t, jsonBuf.String(), `"knee": 1`)
+}
@@ -0,0 +1,524 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/pterm/pterm"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+)
+
+// RenderTimeline writes the benchmark timeline to w as JSON or CSV.
+func RenderTimeline(w io.Writer, b Benchmark, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b.Timeline)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"offset_ns", "query_executions", "query_errors", "qps", "p50_ns", "p99_ns"}); err != nil {
+			return err
+		}
+		for _, point := range b.Timeline {
+			if err := cw.Write([]string{
+				strconv.FormatInt(int64(point.Offset), 10),
+				strconv.Itoa(point.QueryExecutions),
+				strconv.Itoa(point.QueryErrors),
+				strconv.FormatFloat(point.QPS, 'f', -1, 64),
+				strconv.FormatInt(int64(point.P50), 10),
+				strconv.FormatInt(int64(point.P99), 10),
+			}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported timeline format: %s", format)
+	}
+}
+
+// Render writes the benchmark to w in the given format.
+func Render(w io.Writer, b Benchmark, format Format) error {
+	switch format {
+	case FormatText:
+		return renderText(w, b)
+	case FormatJSON:
+		return renderJSON(w, b)
+	case FormatCSV:
+		return renderCSV(w, b)
+	case FormatMarkdown:
+		return renderMarkdown(w, b)
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+type metric struct {
+	label string
+	key   string
+	value interface{}
+}
+
+// metrics returns the summary stats in display order. Duration keys are suffixed with _ns
+// since they are written as nanoseconds in machine-readable formats.
+func (b Benchmark) metrics() []metric {
+	metrics := []metric{
+		{label: "Workers started", key: "workers_started", value: b.WorkersStarted},
+		{label: "Runtime", key: "runtime_ns", value: b.Runtime},
+		{label: "Query processing time (across workers)", key: "query_processing_time_ns", value: b.QueryProcessingTime},
+		{label: "Query executions", key: "query_executions", value: b.QueryExecutions},
+		{label: "Query errors", key: "query_errors", value: b.QueryErrors},
+		{label: "Query timeouts (included in errors)", key: "query_timeouts", value: b.QueryTimeouts},
+	}
+
+	if b.Config.IdleTimeout > 0 {
+		metrics = append(metrics, metric{label: "Workers retired (idle)", key: "workers_retired", value: b.WorkersRetired})
+	}
+
+	if b.Interrupted {
+		metrics = append(metrics,
+			metric{label: "Interrupted", key: "interrupted", value: b.Interrupted},
+			metric{label: "Queries skipped", key: "queries_skipped", value: b.QueriesSkipped},
+		)
+	}
+
+	metrics = append(metrics, []metric{
+		{label: "Min query time", key: "min_query_time_ns", value: b.Latency.Min},
+		{label: "Max query time", key: "max_query_time_ns", value: b.Latency.Max},
+		{label: "Median query time", key: "median_query_time_ns", value: b.Latency.Median},
+		{label: "Average query time", key: "avg_query_time_ns", value: b.Latency.Avg},
+	}...)
+
+	for _, pct := range b.Latency.Percentiles {
+		p := formatPercentile(pct.P)
+		metrics = append(metrics, metric{
+			label: fmt.Sprintf("P%s query time", p),
+			key:   fmt.Sprintf("p%s_query_time_ns", p),
+			value: pct.Value,
+		})
+	}
+
+	if b.ScheduleLag != nil {
+		metrics = append(metrics, []metric{
+			{label: "Median schedule lag", key: "median_schedule_lag_ns", value: b.ScheduleLag.Median},
+			{label: "Average schedule lag", key: "avg_schedule_lag_ns", value: b.ScheduleLag.Avg},
+			{label: "Max schedule lag", key: "max_schedule_lag_ns", value: b.ScheduleLag.Max},
+		}...)
+	}
+
+	if b.Warmup != nil {
+		metrics = append(metrics, []metric{
+			{label: "Warmup query executions", key: "warmup_query_executions", value: b.Warmup.QueryExecutions},
+			{label: "Warmup query errors", key: "warmup_query_errors", value: b.Warmup.QueryErrors},
+			{label: "Warmup median query time", key: "warmup_median_query_time_ns", value: b.Warmup.Latency.Median},
+			{label: "Warmup max query time", key: "warmup_max_query_time_ns", value: b.Warmup.Latency.Max},
+		}...)
+	}
+
+	if b.Verification != nil {
+		metrics = append(metrics, []metric{
+			{label: "Rows returned", key: "rows_returned", value: b.Verification.RowsReturned},
+			{label: "Results matched", key: "results_matched", value: b.Verification.Matched},
+			{label: "Results mismatched", key: "results_mismatched", value: b.Verification.Mismatched},
+			{label: "Results without expected results", key: "results_unknown", value: b.Verification.Unknown},
+			{label: "Result verification errors", key: "result_verification_errors", value: b.Verification.Errors},
+		}...)
+	}
+
+	return metrics
+}
+
+func (m metric) display() string {
+	return fmt.Sprint(m.value)
+}
+
+func (m metric) raw() string {
+	if d, ok := m.value.(time.Duration); ok {
+		return strconv.FormatInt(int64(d), 10)
+	}
+	return fmt.Sprint(m.value)
+}
+
+func formatPercentile(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func renderText(w io.Writer, b Benchmark) error {
+	header := pterm.NewStyle(pterm.FgWhite, pterm.BgDarkGray, pterm.Bold)
+
+	var items []pterm.BulletListItem
+	for _, m := range b.metrics() {
+		items = append(items, pterm.BulletListItem{Text: pterm.Green(m.label+": ") + m.display()})
+	}
+
+	list, err := pterm.DefaultBulletList.WithItems(items).Srender()
+	if err != nil {
+		return err
+	}
+
+	title := "                   Benchmarks                   "
+	if b.Interrupted {
+		title = "            Benchmarks (interrupted)            "
+	}
+
+	if _, err = fmt.Fprintf(w, "\n%s\n%s", header.Sprint(title), list); err != nil {
+		return err
+	}
+
+	if b.Config.PerWorker {
+		if err = renderTextTable(w, header.Sprint("                     Workers                    "), b.workerTable()); err != nil {
+			return err
+		}
+	}
+
+	if len(b.SlowestHosts) > 0 {
+		if err = renderTextTable(w, header.Sprint("                  Slowest hosts                 "), b.hostTable()); err != nil {
+			return err
+		}
+	}
+
+	if b.Verification != nil && len(b.Verification.Mismatches) > 0 {
+		if err = renderTextTable(w, header.Sprint("                Result mismatches               "), b.mismatchTable()); err != nil {
+			return err
+		}
+	}
+
+	if len(b.Timeline) > 0 {
+		if _, err = fmt.Fprintf(w, "\n%s\n%s", header.Sprint("                    Timeline                    "), b.timelineCharts()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func renderTextTable(w io.Writer, title string, data [][]string) error {
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(data).Srender()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "\n%s\n%s\n", title, table)
+	return err
+}
+
+func renderJSON(w io.Writer, b Benchmark) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+func renderCSV(w io.Writer, b Benchmark) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"metric", "value"}); err != nil {
+		return err
+	}
+	for _, m := range b.metrics() {
+		if err := cw.Write([]string{m.key, m.raw()}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func renderMarkdown(w io.Writer, b Benchmark) error {
+	var sb strings.Builder
+	if b.Interrupted {
+		sb.WriteString("## Benchmarks (interrupted)\n\n")
+	} else {
+		sb.WriteString("## Benchmarks\n\n")
+	}
+	sb.WriteString("| Metric | Value |\n")
+	sb.WriteString("|--------|-------|\n")
+	for _, m := range b.metrics() {
+		fmt.Fprintf(&sb, "| %s | %s |\n", m.label, m.display())
+	}
+
+	if b.Config.PerWorker {
+		writeMarkdownTable(&sb, "Workers", b.workerTable())
+	}
+
+	if len(b.SlowestHosts) > 0 {
+		writeMarkdownTable(&sb, "Slowest hosts", b.hostTable())
+	}
+
+	if b.Verification != nil && len(b.Verification.Mismatches) > 0 {
+		writeMarkdownTable(&sb, "Result mismatches", b.mismatchTable())
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeMarkdownTable(sb *strings.Builder, title string, table [][]string) {
+	sb.WriteString("\n## " + title + "\n\n")
+	writeMarkdownRow(sb, table[0])
+	sb.WriteString(strings.Repeat("|---", len(table[0])) + "|\n")
+	for _, row := range table[1:] {
+		writeMarkdownRow(sb, row)
+	}
+}
+
+func writeMarkdownRow(sb *strings.Builder, row []string) {
+	sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+}
+
+// workerTable returns the per-worker breakdown as rows of cells, including a header row.
+func (b Benchmark) workerTable() [][]string {
+	header := []string{"Worker", "Route keys", "Queries", "Errors", "Busy", "Idle", "Min", "Median"}
+	for _, p := range b.Config.Percentiles {
+		header = append(header, "P"+formatPercentile(p))
+	}
+	header = append(header, "Max")
+
+	table := [][]string{header}
+	for _, worker := range b.Workers {
+		row := []string{
+			strconv.Itoa(worker.ID),
+			strconv.Itoa(worker.RouteKeys),
+			strconv.Itoa(worker.QueryExecutions),
+			strconv.Itoa(worker.QueryErrors),
+			worker.BusyTime.String(),
+			worker.IdleTime.String(),
+			worker.Latency.Min.String(),
+			worker.Latency.Median.String(),
+		}
+		for _, pct := range worker.Latency.Percentiles {
+			row = append(row, pct.Value.String())
+		}
+		// Workers without completed queries have no percentiles, so pad to the header width.
+		for len(row) < len(header)-1 {
+			row = append(row, "-")
+		}
+		row = append(row, worker.Latency.Max.String())
+		table = append(table, row)
+	}
+
+	return table
+}
+
+// hostTable returns the slowest hosts as rows of cells, including a header row.
+func (b Benchmark) hostTable() [][]string {
+	table := [][]string{{"Host", "Queries", "Errors", "Min", "Median", "P99", "Max"}}
+	for _, host := range b.SlowestHosts {
+		table = append(table, []string{
+			host.Host,
+			strconv.Itoa(host.QueryExecutions),
+			strconv.Itoa(host.QueryErrors),
+			host.Min.String(),
+			host.Median.String(),
+			host.P99.String(),
+			host.Max.String(),
+		})
+	}
+	return table
+}
+
+// mismatchTable returns the first result mismatches as rows of cells, including a header row.
+func (b Benchmark) mismatchTable() [][]string {
+	table := [][]string{{"Line", "Host", "Start", "End", "Reason"}}
+	for _, mismatch := range b.Verification.Mismatches {
+		table = append(table, []string{
+			strconv.Itoa(mismatch.Line),
+			mismatch.Host,
+			mismatch.Start,
+			mismatch.End,
+			mismatch.Reason,
+		})
+	}
+	return table
+}
+
+// timelineCharts returns ASCII charts of the queries per second and p99 query time of each
+// timeline interval.
+func (b Benchmark) timelineCharts() string {
+	var qps, p99, errs []float64
+	for _, point := range b.Timeline {
+		qps = append(qps, point.QPS)
+		p99 = append(p99, float64(point.P99)/float64(time.Millisecond))
+		errs = append(errs, float64(point.QueryErrors))
+	}
+
+	last := b.Timeline[len(b.Timeline)-1].Offset + b.Config.TimelineInterval
+	charts := asciiChart("Queries per second", qps, last, chartWidth, chartHeight) + "\n" +
+		asciiChart("P99 query time (ms)", p99, last, chartWidth, chartHeight)
+
+	if b.QueryErrors > 0 {
+		charts += "\n" + asciiChart("Query errors", errs, last, chartWidth, chartHeight)
+	}
+
+	return charts
+}
+
+// RenderComparison writes the comparison to w in the given format.
+func RenderComparison(w io.Writer, c Comparison, format Format) error {
+	switch format {
+	case FormatText:
+		table, err := pterm.DefaultTable.WithHasHeader().WithData(c.table()).Srender()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n%s\n", pterm.NewStyle(pterm.FgWhite, pterm.BgDarkGray, pterm.Bold).
+			Sprint("                   Comparison                   "), table)
+		return err
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(c.table()); err != nil {
+			return err
+		}
+		return cw.Error()
+	case FormatMarkdown:
+		var sb strings.Builder
+		writeMarkdownTable(&sb, "Comparison", c.table())
+		_, err := io.WriteString(w, strings.TrimPrefix(sb.String(), "\n"))
+		return err
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// table returns the comparison deltas as rows of cells, including a header row.
+func (c Comparison) table() [][]string {
+	table := [][]string{{"Metric", "Baseline", "Candidate", "Delta", "Delta %", "Threshold", "Status"}}
+	for _, d := range c.Deltas {
+		deltaPercent := "-"
+		if d.DeltaPercent != nil {
+			deltaPercent = fmt.Sprintf("%+.2f%%", *d.DeltaPercent)
+		}
+
+		threshold := "-"
+		if d.Threshold != nil {
+			threshold = formatPercentile(*d.Threshold) + "%"
+		}
+
+		status := "ok"
+		if d.Breached {
+			status = "breached"
+		} else if d.Regressed {
+			status = "regressed"
+		}
+
+		table = append(table, []string{
+			d.Metric,
+			d.format(d.Baseline),
+			d.format(d.Candidate),
+			d.formatDelta(),
+			deltaPercent,
+			threshold,
+			status,
+		})
+	}
+	return table
+}
+
+func (d Delta) format(v float64) string {
+	if d.Duration {
+		return time.Duration(v).String()
+	}
+	if v == math.Trunc(v) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func (d Delta) formatDelta() string {
+	sign := ""
+	if d.Delta > 0 {
+		sign = "+"
+	}
+	return sign + d.format(d.Delta)
+}
+
+// RenderRamp writes the ramp to w in the given format.
+func RenderRamp(w io.Writer, r Ramp, format Format) error {
+	switch format {
+	case FormatText:
+		table, err := pterm.DefaultTable.WithHasHeader().WithData(r.table()).Srender()
+		if err != nil {
+			return err
+		}
+		title := "                      Ramp                      "
+		if r.Interrupted {
+			title = "               Ramp (interrupted)               "
+		}
+		_, err = fmt.Fprintf(w, "\n%s\n%s\n\n%s\n", pterm.NewStyle(pterm.FgWhite, pterm.BgDarkGray, pterm.Bold).
+			Sprint(title), table, r.summary())
+		return err
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(r.table()); err != nil {
+			return err
+		}
+		return cw.Error()
+	case FormatMarkdown:
+		var sb strings.Builder
+		writeMarkdownTable(&sb, "Ramp", r.table())
+		sb.WriteString("\n" + r.summary() + "\n")
+		_, err := io.WriteString(w, strings.TrimPrefix(sb.String(), "\n"))
+		return err
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// table returns the stats of each ramp step as rows of cells, including a header row.
+func (r Ramp) table() [][]string {
+	table := [][]string{{"Step", r.loadLabel(), "Workers started", "QPS", "P50", "P99", "Max", "Queries", "Errors"}}
+	for _, step := range r.Steps {
+		table = append(table, []string{
+			strconv.Itoa(step.Step),
+			strconv.FormatFloat(step.Load, 'f', -1, 64),
+			strconv.Itoa(step.WorkersStarted),
+			strconv.FormatFloat(step.QPS, 'f', 2, 64),
+			step.P50.String(),
+			step.P99.String(),
+			step.Max.String(),
+			strconv.Itoa(step.QueryExecutions),
+			strconv.Itoa(step.QueryErrors),
+		})
+	}
+	return table
+}
+
+func (r Ramp) loadLabel() string {
+	if r.Mode == config.RampRate {
+		return "Target QPS"
+	}
+	return "Workers"
+}
+
+// summary describes the knee of the ramp.
+func (r Ramp) summary() string {
+	if r.Knee == 0 {
+		return "No saturation point found, throughput scaled across all steps"
+	}
+	knee := r.Steps[r.Knee-1]
+	return fmt.Sprintf("Saturation point: step %d (%s %s) at %.2f QPS, throughput stopped scaling or "+
+		"latency climbed beyond it", knee.Step, strconv.FormatFloat(knee.Load, 'f', -1, 64),
+		strings.ToLower(r.loadLabel()), knee.QPS)
+}
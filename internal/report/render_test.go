@@ -0,0 +1,283 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/internal/verify"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestBenchmark() Benchmark {
+	return Benchmark{
+		Config:              config.Config{MaxWorkers: 5},
+		WorkersStarted:      5,
+		Runtime:             time.Second,
+		QueryProcessingTime: 3 * time.Second,
+		QueryExecutions:     200,
+		QueryErrors:         1,
+		Latency: LatencyStats{
+			Min:         time.Millisecond,
+			Max:         200 * time.Millisecond,
+			Median:      12 * time.Millisecond,
+			Avg:         15 * time.Millisecond,
+			Percentiles: []Percentile{{P: 99.9, Value: 150 * time.Millisecond}},
+		},
+		Workers: []WorkerStats{
+			{
+				ID:              1,
+				RouteKeys:       3,
+				QueryExecutions: 200,
+				BusyTime:        3 * time.Second,
+				Latency: LatencyStats{
+					Percentiles: []Percentile{{P: 99.9, Value: 150 * time.Millisecond}},
+				},
+			},
+			{
+				ID: 2,
+			},
+		},
+	}
+}
+
+func TestRender_text(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, newTestBenchmark(), FormatText))
+	assert.Contains(t, buf.String(), "Benchmarks")
+	assert.Contains(t, buf.String(), "Query executions: 200")
+	assert.Contains(t, buf.String(), "P99.9 query time: 150ms")
+}
+
+func TestRender_textPerWorker(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+	b.Config.PerWorker = true
+	b.Config.Percentiles = []float64{99.9}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, b, FormatText))
+	assert.Contains(t, buf.String(), "Workers")
+	assert.Contains(t, buf.String(), "Route keys")
+	assert.Contains(t, buf.String(), "P99.9")
+	assert.Contains(t, buf.String(), "150ms")
+}
+
+func TestRender_json(t *testing.T) {
+	want := newTestBenchmark()
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, want, FormatJSON))
+
+	var got Benchmark
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, want, got)
+}
+
+func TestRender_csv(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, newTestBenchmark(), FormatCSV))
+	assert.Contains(t, buf.String(), "metric,value\n")
+	assert.Contains(t, buf.String(), "query_executions,200\n")
+	assert.Contains(t, buf.String(), "p99.9_query_time_ns,150000000\n")
+}
+
+func TestRender_markdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, newTestBenchmark(), FormatMarkdown))
+	assert.Contains(t, buf.String(), "| Metric | Value |\n")
+	assert.Contains(t, buf.String(), "| Query executions | 200 |\n")
+	assert.Contains(t, buf.String(), "| P99.9 query time | 150ms |\n")
+}
+
+func TestRender_markdownPerWorker(t *testing.T) {
+	b := newTestBenchmark()
+	b.Config.PerWorker = true
+	b.Config.Percentiles = []float64{99.9}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, b, FormatMarkdown))
+	assert.Contains(t, buf.String(), "## Workers\n\n")
+	assert.Contains(t, buf.String(), "| Worker | Route keys | Queries | Errors | Busy | Idle | Min | Median | P99.9 | Max |\n")
+	assert.Contains(t, buf.String(), "| 1 | 3 | 200 | 0 | 3s | 0s | 0s | 0s | 150ms | 0s |\n")
+	assert.Contains(t, buf.String(), "| 2 | 0 | 0 | 0 | 0s | 0s | 0s | 0s | - | 0s |\n")
+}
+
+func TestRender_slowestHosts(t *testing.T) {
+	b := newTestBenchmark()
+	b.SlowestHosts = []HostStats{{Host: "host_000001", QueryExecutions: 3, P99: 30 * time.Millisecond}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, b, FormatMarkdown))
+	assert.Contains(t, buf.String(), "## Slowest hosts\n\n")
+	assert.Contains(t, buf.String(), "| host_000001 | 3 | 0 | 0s | 0s | 30ms | 0s |\n")
+
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	buf.Reset()
+	require.NoError(t, Render(&buf, b, FormatText))
+	assert.Contains(t, buf.String(), "Slowest hosts")
+	assert.Contains(t, buf.String(), "host_000001")
+}
+
+func TestRender_timeline(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+	b.Config.TimelineInterval = time.Second
+	b.Timeline = []TimelinePoint{
+		{Offset: 0, QueryExecutions: 10, QPS: 10, P99: 5 * time.Millisecond},
+		{Offset: time.Second, QueryExecutions: 20, QPS: 20, P99: 10 * time.Millisecond},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, b, FormatText))
+	assert.Contains(t, buf.String(), "Timeline")
+	assert.Contains(t, buf.String(), "Queries per second\n20.0 ┤ █")
+	assert.Contains(t, buf.String(), "P99 query time (ms)\n10.0 ┤ █")
+	assert.Contains(t, buf.String(), "Query errors")
+}
+
+func TestRenderTimeline(t *testing.T) {
+	b := newTestBenchmark()
+	b.Timeline = []TimelinePoint{
+		{Offset: time.Second, QueryExecutions: 20, QueryErrors: 1, QPS: 20, P50: time.Millisecond, P99: 2 * time.Millisecond},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderTimeline(&buf, b, FormatCSV))
+	assert.Equal(t, "offset_ns,query_executions,query_errors,qps,p50_ns,p99_ns\n"+
+		"1000000000,20,1,20,1000000,2000000\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, RenderTimeline(&buf, b, FormatJSON))
+	var got []TimelinePoint
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, b.Timeline, got)
+
+	assert.EqualError(t, RenderTimeline(&buf, b, FormatMarkdown), "unsupported timeline format: markdown")
+}
+
+func TestRender_interrupted(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+	b.Interrupted = true
+	b.QueriesSkipped = 42
+
+	var text bytes.Buffer
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.Contains(t, text.String(), "Benchmarks (interrupted)")
+	assert.Contains(t, text.String(), "Queries skipped: 42")
+
+	var csv bytes.Buffer
+	require.NoError(t, Render(&csv, b, FormatCSV))
+	assert.Contains(t, csv.String(), "interrupted,true\nqueries_skipped,42\n")
+
+	var markdown bytes.Buffer
+	require.NoError(t, Render(&markdown, b, FormatMarkdown))
+	assert.Contains(t, markdown.String(), "## Benchmarks (interrupted)")
+}
+
+func TestRender_scheduleLag(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+	b.ScheduleLag = &LatencyStats{Median: time.Millisecond, Avg: 2 * time.Millisecond, Max: time.Second}
+
+	var text bytes.Buffer
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.Contains(t, text.String(), "Max schedule lag: 1s")
+
+	var csv bytes.Buffer
+	require.NoError(t, Render(&csv, b, FormatCSV))
+	assert.Contains(t, csv.String(), "avg_schedule_lag_ns,2000000\n")
+}
+
+func TestRender_warmup(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+	b.Warmup = &WarmupStats{QueryExecutions: 10, Latency: LatencyStats{Max: time.Second}}
+
+	var text bytes.Buffer
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.Contains(t, text.String(), "Warmup query executions: 10")
+	assert.Contains(t, text.String(), "Warmup max query time: 1s")
+}
+
+func TestRender_workersRetired(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+	b.WorkersRetired = 3
+
+	var text bytes.Buffer
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.NotContains(t, text.String(), "Workers retired")
+
+	b.Config.IdleTimeout = time.Minute
+
+	text.Reset()
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.Contains(t, text.String(), "Workers retired (idle): 3")
+
+	var csv bytes.Buffer
+	require.NoError(t, Render(&csv, b, FormatCSV))
+	assert.Contains(t, csv.String(), "workers_retired,3\n")
+}
+
+func TestRender_verification(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+
+	b := newTestBenchmark()
+
+	var text bytes.Buffer
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.NotContains(t, text.String(), "Rows returned")
+
+	b.Verification = &verify.Summary{
+		RowsReturned: 11940,
+		Matched:      198,
+		Mismatched:   1,
+		Mismatches: []verify.Mismatch{{
+			Line:   7,
+			Query:  verify.Query{Host: "host_000008", Start: "2017-01-01 08:59:22", End: "2017-01-01 09:59:22"},
+			Reason: "returned 59 rows, want 60",
+		}},
+	}
+
+	text.Reset()
+	require.NoError(t, Render(&text, b, FormatText))
+	assert.Contains(t, text.String(), "Rows returned: 11940")
+	assert.Contains(t, text.String(), "Result mismatches")
+	assert.Contains(t, text.String(), "returned 59 rows, want 60")
+
+	var markdown bytes.Buffer
+	require.NoError(t, Render(&markdown, b, FormatMarkdown))
+	assert.Contains(t, markdown.String(), "| 7 | host_000008 | 2017-01-01 08:59:22 | 2017-01-01 09:59:22 | returned 59 rows, want 60 |")
+
+	var csv bytes.Buffer
+	require.NoError(t, Render(&csv, b, FormatCSV))
+	assert.Contains(t, csv.String(), "results_mismatched,1\n")
+}
+
+func TestRender_unknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	assert.EqualError(t, Render(&buf, newTestBenchmark(), "xml"), "unknown output format: xml")
+}
@@ -0,0 +1,227 @@
+package report
+
+import (
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/internal/verify"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"github.com/joshjon/tsbenchmark/pkg/stats"
+	"go.uber.org/zap"
+	"sort"
+	"time"
+)
+
+// Benchmark is the summary of a benchmark run. Durations are encoded as nanoseconds in JSON.
+type Benchmark struct {
+	Config              config.Config   `json:"config"`
+	Interrupted         bool            `json:"interrupted"`
+	QueriesSkipped      int64           `json:"queries_skipped"`
+	WorkersStarted      int             `json:"workers_started"`
+	WorkersRetired      int             `json:"workers_retired"`
+	Runtime             time.Duration   `json:"runtime_ns"`
+	QueryProcessingTime time.Duration   `json:"query_processing_time_ns"`
+	QueryExecutions     int             `json:"query_executions"`
+	QueryErrors         int             `json:"query_errors"`
+	QueryTimeouts       int             `json:"query_timeouts"`
+	Latency             LatencyStats    `json:"latency"`
+	ScheduleLag         *LatencyStats   `json:"schedule_lag,omitempty"`
+	Warmup              *WarmupStats    `json:"warmup,omitempty"`
+	Workers             []WorkerStats   `json:"workers"`
+	SlowestHosts        []HostStats     `json:"slowest_hosts,omitempty"`
+	Timeline            []TimelinePoint `json:"timeline,omitempty"`
+	Verification        *verify.Summary `json:"verification,omitempty"`
+}
+
+// LatencyStats summarises the query times recorded in a histogram.
+type LatencyStats struct {
+	Min         time.Duration `json:"min_ns"`
+	Max         time.Duration `json:"max_ns"`
+	Median      time.Duration `json:"median_ns"`
+	Avg         time.Duration `json:"avg_ns"`
+	Percentiles []Percentile  `json:"percentiles"`
+}
+
+type Percentile struct {
+	P     float64       `json:"p"`
+	Value time.Duration `json:"value_ns"`
+}
+
+// WarmupStats summarises the queries executed during the warmup phase, which are excluded from
+// all other stats.
+type WarmupStats struct {
+	QueryExecutions     int           `json:"query_executions"`
+	QueryErrors         int           `json:"query_errors"`
+	QueryProcessingTime time.Duration `json:"query_processing_time_ns"`
+	Latency             LatencyStats  `json:"latency"`
+	// Elapsed is how long the warmup phase lasted, which is included in the runtime.
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// WorkerStats is the breakdown of a single worker's contribution to the benchmark.
+type WorkerStats struct {
+	ID              int           `json:"id"`
+	RouteKeys       int           `json:"route_keys"`
+	QueryExecutions int           `json:"query_executions"`
+	QueryErrors     int           `json:"query_errors"`
+	BusyTime        time.Duration `json:"busy_time_ns"`
+	IdleTime        time.Duration `json:"idle_time_ns"`
+	Latency         LatencyStats  `json:"latency"`
+}
+
+// HostStats is the breakdown of all queries executed for a single host.
+type HostStats struct {
+	Host            string        `json:"host"`
+	QueryExecutions int           `json:"query_executions"`
+	QueryErrors     int           `json:"query_errors"`
+	Min             time.Duration `json:"min_ns"`
+	Median          time.Duration `json:"median_ns"`
+	P99             time.Duration `json:"p99_ns"`
+	Max             time.Duration `json:"max_ns"`
+}
+
+// TimelinePoint holds the stats of queries that completed within a single timeline interval.
+// Offset is the start of the interval relative to the start of the run.
+type TimelinePoint struct {
+	Offset          time.Duration `json:"offset_ns"`
+	QueryExecutions int           `json:"query_executions"`
+	QueryErrors     int           `json:"query_errors"`
+	QPS             float64       `json:"qps"`
+	P50             time.Duration `json:"p50_ns"`
+	P99             time.Duration `json:"p99_ns"`
+}
+
+// New creates a benchmark from the results of a pool run. Any password in the config database
+// connection is redacted. If the run was interrupted, the benchmark only covers the queries that
+// were executed.
+func New(cfg config.Config, runtime time.Duration, result *pool.Result) Benchmark {
+	b := Benchmark{
+		Config:         cfg.Redacted(),
+		Interrupted:    result.Interrupted,
+		QueriesSkipped: result.Skipped,
+		WorkersStarted: len(result.Workers),
+		Runtime:        runtime,
+		Latency:        newLatencyStats(result.Latencies, cfg.Percentiles),
+	}
+
+	for _, workerResult := range result.Workers {
+		b.QueryExecutions += workerResult.Completed
+		b.QueryProcessingTime += workerResult.TotalDuration
+		b.QueryErrors += workerResult.Errors
+		b.QueryTimeouts += workerResult.Timeouts
+		if workerResult.Retired {
+			b.WorkersRetired += 1
+		}
+
+		for _, taskErr := range workerResult.ErrorSamples {
+			zap.L().Error("query error", zap.Int("worker_id", workerResult.WorkerID), zap.Error(taskErr))
+		}
+
+		b.Workers = append(b.Workers, WorkerStats{
+			ID:              workerResult.WorkerID,
+			RouteKeys:       workerResult.RouteKeys,
+			QueryExecutions: workerResult.Completed,
+			QueryErrors:     workerResult.Errors,
+			BusyTime:        workerResult.TotalDuration,
+			IdleTime:        workerResult.IdleDuration(),
+			Latency:         newLatencyStats(workerResult.Latencies, cfg.Percentiles),
+		})
+	}
+
+	if result.ScheduleLag != nil {
+		lag := newLatencyStats(result.ScheduleLag, cfg.Percentiles)
+		b.ScheduleLag = &lag
+	}
+
+	if result.Warmup != nil {
+		b.Warmup = &WarmupStats{
+			QueryExecutions:     result.Warmup.Completed,
+			QueryErrors:         result.Warmup.Errors,
+			QueryProcessingTime: result.Warmup.TotalDuration,
+			Latency:             newLatencyStats(result.Warmup.Latencies, cfg.Percentiles),
+			Elapsed:             result.Warmup.Elapsed,
+		}
+	}
+
+	b.SlowestHosts = slowestHosts(result.RouteKeys, cfg.TopHosts)
+	b.Timeline = newTimeline(result.Timeline)
+
+	return b
+}
+
+// slowestHosts returns the stats of the n hosts with the highest p99 query time.
+func slowestHosts(routeKeys map[string]*pool.RouteKeyResult, n int) []HostStats {
+	if n <= 0 {
+		return nil
+	}
+
+	var hosts []HostStats
+	for host, result := range routeKeys {
+		hosts = append(hosts, HostStats{
+			Host:            host,
+			QueryExecutions: result.Completed,
+			QueryErrors:     result.Errors,
+			Min:             result.Latencies.Min(),
+			Median:          result.Latencies.Percentile(50),
+			P99:             result.Latencies.Percentile(99),
+			Max:             result.Latencies.Max(),
+		})
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].P99 == hosts[j].P99 {
+			return hosts[i].Host < hosts[j].Host
+		}
+		return hosts[i].P99 > hosts[j].P99
+	})
+
+	if len(hosts) > n {
+		hosts = hosts[:n]
+	}
+	return hosts
+}
+
+func newTimeline(timeline *stats.Timeline) []TimelinePoint {
+	if timeline == nil {
+		return nil
+	}
+
+	var points []TimelinePoint
+	for _, bucket := range timeline.Buckets() {
+		points = append(points, TimelinePoint{
+			Offset:          bucket.Offset,
+			QueryExecutions: bucket.Completed,
+			QueryErrors:     bucket.Errors,
+			QPS:             float64(bucket.Completed) / timeline.Interval().Seconds(),
+			P50:             bucket.Latencies.Percentile(50),
+			P99:             bucket.Latencies.Percentile(99),
+		})
+	}
+
+	return points
+}
+
+func newLatencyStats(h *stats.Histogram, percentiles []float64) LatencyStats {
+	if h == nil || h.Count() == 0 {
+		return LatencyStats{}
+	}
+
+	l := LatencyStats{
+		Min:    h.Min(),
+		Max:    h.Max(),
+		Median: h.Percentile(50),
+		Avg:    h.Mean(),
+	}
+
+	for _, p := range percentiles {
+		l.Percentiles = append(l.Percentiles, Percentile{P: p, Value: h.Percentile(p)})
+	}
+
+	return l
+}
+
+// measuredRuntime returns the runtime excluding the warmup phase, which QPS is measured over.
+func (b Benchmark) measuredRuntime() time.Duration {
+	if b.Warmup != nil {
+		return b.Runtime - b.Warmup.Elapsed
+	}
+	return b.Runtime
+}
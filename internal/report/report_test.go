@@ -0,0 +1,216 @@
+package report
+
+import (
+	"github.com/joshjon/tsbenchmark/internal/config"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"github.com/joshjon/tsbenchmark/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestResult(durations ...[]time.Duration) *pool.Result {
+	result := &pool.Result{
+		Latencies: stats.NewHistogram(stats.HistogramConfig{}),
+	}
+
+	for i, workerDurations := range durations {
+		workerResult := &pool.WorkerResult{
+			WorkerID:  i + 1,
+			RouteKeys: 1,
+			Started:   time.Now(),
+			Latencies: stats.NewHistogram(stats.HistogramConfig{}),
+		}
+		for _, d := range workerDurations {
+			workerResult.Completed++
+			workerResult.TotalDuration += d
+			workerResult.Latencies.Record(d)
+		}
+		workerResult.Stopped = workerResult.Started.Add(time.Second)
+		result.Workers = append(result.Workers, workerResult)
+		result.Latencies.Merge(workerResult.Latencies)
+	}
+
+	return result
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.Config{
+		DatabaseConnection: "host=localhost password=secret",
+		Percentiles:        []float64{99},
+	}
+	result := newTestResult(
+		[]time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		[]time.Duration{30 * time.Millisecond, 40 * time.Millisecond},
+	)
+	result.Workers[1].Errors = 1
+
+	b := New(cfg, time.Second, result)
+
+	assert.Equal(t, "host=localhost password=xxxxx", b.Config.DatabaseConnection)
+	assert.Equal(t, 2, b.WorkersStarted)
+	assert.Equal(t, time.Second, b.Runtime)
+	assert.Equal(t, 4, b.QueryExecutions)
+	assert.Equal(t, 1, b.QueryErrors)
+	assert.Equal(t, 100*time.Millisecond, b.QueryProcessingTime)
+	assert.Equal(t, 10*time.Millisecond, b.Latency.Min)
+	assert.Equal(t, 40*time.Millisecond, b.Latency.Max)
+	assert.Equal(t, 25*time.Millisecond, b.Latency.Avg)
+	require.Len(t, b.Latency.Percentiles, 1)
+	assert.Equal(t, 99.0, b.Latency.Percentiles[0].P)
+	assert.InEpsilon(t, 39700*time.Microsecond, b.Latency.Percentiles[0].Value, 0.01)
+
+	assert.Len(t, b.Workers, 2)
+	assert.Equal(t, 1, b.Workers[0].ID)
+	assert.Equal(t, 1, b.Workers[0].RouteKeys)
+	assert.Equal(t, 30*time.Millisecond, b.Workers[0].BusyTime)
+	assert.Equal(t, 970*time.Millisecond, b.Workers[0].IdleTime)
+	assert.Equal(t, 2, b.Workers[0].QueryExecutions)
+	assert.Equal(t, 0, b.Workers[0].QueryErrors)
+	assert.Equal(t, 1, b.Workers[1].QueryErrors)
+	assert.Equal(t, 30*time.Millisecond, b.Workers[1].Latency.Min)
+}
+
+func TestNew_interrupted(t *testing.T) {
+	result := newTestResult([]time.Duration{10 * time.Millisecond})
+	result.Interrupted = true
+	result.Skipped = 5
+
+	b := New(config.Config{}, time.Second, result)
+	assert.True(t, b.Interrupted)
+	assert.Equal(t, int64(5), b.QueriesSkipped)
+	assert.Equal(t, 1, b.QueryExecutions)
+}
+
+func TestNew_workersRetired(t *testing.T) {
+	result := newTestResult([]time.Duration{10 * time.Millisecond}, []time.Duration{20 * time.Millisecond})
+	result.Workers[0].Retired = true
+
+	b := New(config.Config{}, time.Second, result)
+	assert.Equal(t, 2, b.WorkersStarted)
+	assert.Equal(t, 1, b.WorkersRetired)
+}
+
+func TestNew_queryTimeouts(t *testing.T) {
+	result := newTestResult(
+		[]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond},
+		[]time.Duration{40 * time.Millisecond},
+	)
+	result.Workers[0].Errors = 3
+	result.Workers[0].Timeouts = 2
+	result.Workers[1].Errors = 1
+	result.Workers[1].Timeouts = 1
+
+	b := New(config.Config{}, time.Second, result)
+	assert.Equal(t, 4, b.QueryErrors)
+	assert.Equal(t, 3, b.QueryTimeouts)
+}
+
+func TestNew_scheduleLag(t *testing.T) {
+	result := newTestResult([]time.Duration{10 * time.Millisecond})
+	assert.Nil(t, New(config.Config{}, time.Second, result).ScheduleLag)
+
+	result.ScheduleLag = stats.NewHistogram(stats.HistogramConfig{})
+	result.ScheduleLag.Record(100 * time.Microsecond)
+	result.ScheduleLag.Record(200 * time.Microsecond)
+
+	b := New(config.Config{}, time.Second, result)
+	require.NotNil(t, b.ScheduleLag)
+	assert.Equal(t, 100*time.Microsecond, b.ScheduleLag.Min)
+	assert.Equal(t, 200*time.Microsecond, b.ScheduleLag.Max)
+}
+
+func TestNew_warmup(t *testing.T) {
+	result := newTestResult([]time.Duration{10 * time.Millisecond})
+	assert.Nil(t, New(config.Config{}, time.Second, result).Warmup)
+
+	result.Warmup = &pool.WarmupResult{
+		Completed:     2,
+		Errors:        1,
+		TotalDuration: 300 * time.Microsecond,
+		Latencies:     stats.NewHistogram(stats.HistogramConfig{}),
+		Elapsed:       250 * time.Millisecond,
+	}
+	result.Warmup.Latencies.Record(100 * time.Microsecond)
+	result.Warmup.Latencies.Record(200 * time.Microsecond)
+
+	b := New(config.Config{}, time.Second, result)
+	require.NotNil(t, b.Warmup)
+	assert.Equal(t, 2, b.Warmup.QueryExecutions)
+	assert.Equal(t, 1, b.Warmup.QueryErrors)
+	assert.Equal(t, 300*time.Microsecond, b.Warmup.QueryProcessingTime)
+	assert.Equal(t, 200*time.Microsecond, b.Warmup.Latency.Max)
+	assert.Equal(t, 250*time.Millisecond, b.Warmup.Elapsed)
+	assert.Equal(t, 750*time.Millisecond, b.measuredRuntime())
+	assert.Equal(t, 1, b.QueryExecutions)
+}
+
+func TestNew_noQueries(t *testing.T) {
+	b := New(config.Config{}, time.Second, newTestResult())
+	assert.Zero(t, b.QueryExecutions)
+	assert.Equal(t, LatencyStats{}, b.Latency)
+}
+
+func TestNew_slowestHosts(t *testing.T) {
+	result := newTestResult()
+	result.RouteKeys = make(map[string]*pool.RouteKeyResult)
+	for host, durations := range map[string][]time.Duration{
+		"host_1": {time.Millisecond, 2 * time.Millisecond},
+		"host_2": {50 * time.Millisecond},
+		"host_3": {10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond},
+	} {
+		routeKeyResult := &pool.RouteKeyResult{Latencies: stats.NewHistogram(stats.HistogramConfig{})}
+		for _, d := range durations {
+			routeKeyResult.Completed++
+			routeKeyResult.Latencies.Record(d)
+		}
+		result.RouteKeys[host] = routeKeyResult
+	}
+	result.RouteKeys["host_3"].Errors = 1
+
+	b := New(config.Config{TopHosts: 2}, time.Second, result)
+
+	require.Len(t, b.SlowestHosts, 2)
+	assert.Equal(t, HostStats{
+		Host:            "host_2",
+		QueryExecutions: 1,
+		Min:             50 * time.Millisecond,
+		Median:          50 * time.Millisecond,
+		P99:             50 * time.Millisecond,
+		Max:             50 * time.Millisecond,
+	}, b.SlowestHosts[0])
+
+	host := b.SlowestHosts[1]
+	assert.Equal(t, "host_3", host.Host)
+	assert.Equal(t, 3, host.QueryExecutions)
+	assert.Equal(t, 1, host.QueryErrors)
+	assert.Equal(t, 10*time.Millisecond, host.Min)
+	assert.InEpsilon(t, 20*time.Millisecond, host.Median, 0.01)
+	assert.InEpsilon(t, 29800*time.Microsecond, host.P99, 0.01)
+	assert.Equal(t, 30*time.Millisecond, host.Max)
+}
+
+func TestNew_timeline(t *testing.T) {
+	start := time.Now()
+	result := newTestResult()
+	result.Timeline = stats.NewTimeline(start, 500*time.Millisecond, stats.HistogramConfig{})
+	result.Timeline.Record(start.Add(100*time.Millisecond), 100*time.Microsecond, false)
+	result.Timeline.Record(start.Add(200*time.Millisecond), 200*time.Microsecond, true)
+	result.Timeline.Record(start.Add(1100*time.Millisecond), 300*time.Microsecond, false)
+
+	b := New(config.Config{}, time.Second, result)
+
+	require.Len(t, b.Timeline, 3)
+	assert.Equal(t, TimelinePoint{
+		Offset:          0,
+		QueryExecutions: 2,
+		QueryErrors:     1,
+		QPS:             4,
+		P50:             150 * time.Microsecond,
+		P99:             199 * time.Microsecond,
+	}, b.Timeline[0])
+	assert.Equal(t, TimelinePoint{Offset: 500 * time.Millisecond}, b.Timeline[1])
+	assert.Equal(t, time.Second, b.Timeline[2].Offset)
+	assert.Equal(t, float64(2), b.Timeline[2].QPS)
+}
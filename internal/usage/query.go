@@ -1,6 +1,7 @@
 package usage
 
 import (
+	"context"
 	"database/sql"
 )
 
@@ -18,9 +19,10 @@ type Result struct {
 }
 
 // QueryMinMaxUsagePerMinuteInRange returns the max cpu usage and min cpu usage of the given
-// hostname for every minute in the time range specified by the start time and end time.
-func QueryMinMaxUsagePerMinuteInRange(db *sql.DB, host string, startTimestamp string, endTimestamp string) ([]Result, error) {
-	rows, err := db.Query(query, host, startTimestamp, endTimestamp)
+// hostname for every minute in the time range specified by the start time and end time. The
+// query is cancelled if ctx is cancelled before it completes.
+func QueryMinMaxUsagePerMinuteInRange(ctx context.Context, db *sql.DB, host string, startTimestamp string, endTimestamp string) ([]Result, error) {
+	rows, err := db.QueryContext(ctx, query, host, startTimestamp, endTimestamp)
 	if err != nil {
 		return nil, err
 	}
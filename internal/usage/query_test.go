@@ -1,6 +1,7 @@
 package usage
 
 import (
+	"context"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,7 +24,7 @@ func TestQueryMinMaxUsagePerMinuteInRange(t *testing.T) {
 		AddRow(wantInterval, float64(20), float64(40), host, 1)
 
 	mock.ExpectQuery(".*").WillReturnRows(rows)
-	results, err := QueryMinMaxUsagePerMinuteInRange(db, host, start, end)
+	results, err := QueryMinMaxUsagePerMinuteInRange(context.Background(), db, host, start, end)
 	require.NoError(t, err)
 	require.Len(t, results, 1)
 
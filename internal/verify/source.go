@@ -0,0 +1,78 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"github.com/joshjon/tsbenchmark/internal/rawlog"
+	"github.com/joshjon/tsbenchmark/internal/usage"
+	"sync"
+)
+
+// fileSource holds the number of rows returned by each query of a previous run.
+type fileSource map[Query]int
+
+// LoadFile returns a Source of the row counts recorded in the raw log of a previous run, which only
+// verifies the number of rows each query returns. Queries that failed in the previous run are
+// ignored, and the first row count recorded for a query is used.
+func LoadFile(path string) (Source, error) {
+	records, err := rawlog.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := make(fileSource)
+	for _, record := range records {
+		query := Query{Host: record.Host, Start: record.Start, End: record.End}
+		if _, ok := source[query]; ok || record.Error != "" {
+			continue
+		}
+		source[query] = record.Rows
+	}
+	return source, nil
+}
+
+func (s fileSource) Expected(_ context.Context, query Query) (Expected, bool, error) {
+	rows, ok := s[query]
+	return Expected{Rows: rows}, ok, nil
+}
+
+// databaseSource executes each query against a reference database, caching the results of
+// queries that are repeated.
+type databaseSource struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	cache map[Query]Expected
+}
+
+// NewDatabaseSource returns a Source that executes each query against a reference database,
+// which verifies every row each query returns. The database is closed when the source is closed.
+func NewDatabaseSource(db *sql.DB) Source {
+	return &databaseSource{
+		db:    db,
+		cache: make(map[Query]Expected),
+	}
+}
+
+func (s *databaseSource) Expected(ctx context.Context, query Query) (Expected, bool, error) {
+	s.mu.Lock()
+	expected, ok := s.cache[query]
+	s.mu.Unlock()
+	if ok {
+		return expected, true, nil
+	}
+
+	results, err := usage.QueryMinMaxUsagePerMinuteInRange(ctx, s.db, query.Host, query.Start, query.End)
+	if err != nil {
+		return Expected{}, false, err
+	}
+
+	expected = Expected{Rows: len(results), Results: results}
+	s.mu.Lock()
+	s.cache[query] = expected
+	s.mu.Unlock()
+	return expected, true, nil
+}
+
+func (s *databaseSource) Close() error {
+	return s.db.Close()
+}
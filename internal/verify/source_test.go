@@ -0,0 +1,63 @@
+package verify
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joshjon/tsbenchmark/internal/rawlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.csv")
+	w, err := rawlog.Create(path)
+	require.NoError(t, err)
+	w.Write(rawlog.Record{Line: 2, Host: "a", Start: "s", End: "e", Error: "timeout"})
+	w.Write(rawlog.Record{Line: 2, Host: "a", Start: "s", End: "e", Rows: 60})
+	w.Write(rawlog.Record{Line: 2, Host: "a", Start: "s", End: "e", Rows: 59})
+	require.NoError(t, w.Close())
+
+	source, err := LoadFile(path)
+	require.NoError(t, err)
+
+	expected, ok, err := source.Expected(context.Background(), Query{Host: "a", Start: "s", End: "e"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Expected{Rows: 60}, expected)
+
+	_, ok, err = source.Expected(context.Background(), Query{Host: "b", Start: "s", End: "e"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLoadFile_notFound(t *testing.T) {
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.Error(t, err)
+}
+
+func TestDatabaseSource(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"time", "min", "max", "host", "count"}).
+		AddRow("2017-01-01 09:00:00", float64(10), float64(20), "a", 6).
+		AddRow("2017-01-01 09:01:00", float64(15), float64(30), "a", 6)
+	mock.ExpectQuery(".*").WillReturnRows(rows)
+
+	source := NewDatabaseSource(db)
+	query := Query{Host: "a", Start: "2017-01-01 09:00:00", End: "2017-01-01 09:01:00"}
+
+	for i := 0; i < 2; i++ {
+		expected, ok, err := source.Expected(context.Background(), query)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 2, expected.Rows)
+		assert.Len(t, expected.Results, 2)
+	}
+
+	mock.ExpectClose()
+	New(context.Background(), source, 1).Close()
+	assert.NoError(t, mock.ExpectationsWereMet(), "repeated queries should be cached and the database closed")
+}
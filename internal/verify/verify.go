@@ -0,0 +1,181 @@
+// Package verify checks the results of benchmark queries against expected results, so that a
+// benchmark also catches correctness regressions such as a broken continuous aggregate returning
+// fewer buckets.
+package verify
+
+import (
+	"context"
+	"fmt"
+	"github.com/joshjon/tsbenchmark/internal/usage"
+	"go.uber.org/zap"
+	"io"
+	"sort"
+	"sync"
+)
+
+// bufferSize is the number of results that can be queued for verification before Verify blocks.
+const bufferSize = 10000
+
+// maxMismatches is the number of mismatches retained in a summary.
+const maxMismatches = 10
+
+// Query identifies a query by its params.
+type Query struct {
+	Host  string `json:"hostname"`
+	Start string `json:"start_time"`
+	End   string `json:"end_time"`
+}
+
+// Expected is the expected result of a query. Results is nil if only the number of rows is known.
+type Expected struct {
+	Rows    int
+	Results []usage.Result
+}
+
+// Source looks up the expected result of a query, returning false if the query is unknown.
+type Source interface {
+	Expected(ctx context.Context, query Query) (Expected, bool, error)
+}
+
+// Mismatch is a query that returned unexpected results.
+type Mismatch struct {
+	// Line is the line number of the query in the CSV file.
+	Line int `json:"line"`
+	Query
+	Reason string `json:"reason"`
+}
+
+// Summary is the outcome of verifying the results of all successful queries.
+type Summary struct {
+	// RowsReturned is the total number of rows returned by the queries.
+	RowsReturned int `json:"rows_returned"`
+	Matched      int `json:"matched"`
+	Mismatched   int `json:"mismatched"`
+	// Unknown is the number of queries without an expected result.
+	Unknown int `json:"unknown"`
+	// Errors is the number of queries whose expected result could not be looked up.
+	Errors int `json:"errors"`
+	// Mismatches holds the first mismatches found.
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+type check struct {
+	line    int
+	query   Query
+	results []usage.Result
+}
+
+// Verifier compares query results with their expected results in the background, so that looking
+// up expected results does not delay the worker that executed the query.
+type Verifier struct {
+	source    Source
+	checks    chan check
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	summary   Summary
+	closeOnce sync.Once
+}
+
+// New returns a Verifier that looks up expected results from the source with the given
+// concurrency until ctx is cancelled. The source is closed with the verifier if it is an
+// io.Closer.
+func New(ctx context.Context, source Source, concurrency int) *Verifier {
+	v := &Verifier{
+		source: source,
+		checks: make(chan check, bufferSize),
+	}
+
+	for i := 0; i < concurrency; i++ {
+		v.wg.Add(1)
+		go func() {
+			defer v.wg.Done()
+			for c := range v.checks {
+				v.check(ctx, c)
+			}
+		}()
+	}
+
+	return v
+}
+
+// Verify queues the results of the query on the given CSV line to be verified. It must not be
+// called after Close.
+func (v *Verifier) Verify(line int, query Query, results []usage.Result) {
+	v.checks <- check{line: line, query: query, results: results}
+}
+
+// Close waits for all queued results to be verified, closes the source and returns the summary.
+// It may be called more than once.
+func (v *Verifier) Close() Summary {
+	v.closeOnce.Do(func() {
+		close(v.checks)
+		v.wg.Wait()
+
+		if closer, ok := v.source.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				zap.L().Debug("error closing expected results source", zap.Error(err))
+			}
+		}
+		zap.L().Debug("finished verifying results", zap.Int("matched", v.summary.Matched),
+			zap.Int("mismatched", v.summary.Mismatched))
+	})
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.summary
+}
+
+func (v *Verifier) check(ctx context.Context, c check) {
+	expected, ok, err := v.source.Expected(ctx, c.query)
+	if err != nil {
+		zap.L().Debug("error looking up expected result", zap.Any("query", c.query), zap.Error(err))
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.summary.RowsReturned += len(c.results)
+	switch {
+	case err != nil:
+		v.summary.Errors++
+	case !ok:
+		v.summary.Unknown++
+	default:
+		if reason := Compare(c.results, expected); reason != "" {
+			v.summary.Mismatched++
+			if len(v.summary.Mismatches) < maxMismatches {
+				v.summary.Mismatches = append(v.summary.Mismatches, Mismatch{Line: c.line, Query: c.query, Reason: reason})
+			}
+		} else {
+			v.summary.Matched++
+		}
+	}
+}
+
+// Compare returns the reason the results differ from the expected result, or an empty string if
+// they match. Results are compared regardless of their order.
+func Compare(results []usage.Result, expected Expected) string {
+	if len(results) != expected.Rows {
+		return fmt.Sprintf("returned %d rows, want %d", len(results), expected.Rows)
+	}
+	if expected.Results == nil {
+		return ""
+	}
+
+	got, want := sorted(results), sorted(expected.Results)
+	for i := range want {
+		if got[i] != want[i] {
+			return fmt.Sprintf("returned %+v, want %+v", got[i], want[i])
+		}
+	}
+	return ""
+}
+
+// sorted returns a copy of results sorted by interval.
+func sorted(results []usage.Result) []usage.Result {
+	s := append([]usage.Result(nil), results...)
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Interval < s[j].Interval
+	})
+	return s
+}
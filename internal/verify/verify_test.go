@@ -0,0 +1,96 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"github.com/joshjon/tsbenchmark/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func newTestResults() []usage.Result {
+	return []usage.Result{
+		{Count: 6, Interval: "2017-01-01 09:00:00", Host: "host_000008", Min: 10, Max: 20},
+		{Count: 6, Interval: "2017-01-01 09:01:00", Host: "host_000008", Min: 15, Max: 30},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	changed := newTestResults()
+	changed[1].Max = 31
+
+	tests := []struct {
+		name     string
+		results  []usage.Result
+		expected Expected
+		want     string
+	}{
+		{
+			name:     "matching row count",
+			results:  newTestResults(),
+			expected: Expected{Rows: 2},
+		},
+		{
+			name:     "fewer rows",
+			results:  newTestResults()[:1],
+			expected: Expected{Rows: 2},
+			want:     "returned 1 rows, want 2",
+		},
+		{
+			name:     "matching results in a different order",
+			results:  []usage.Result{newTestResults()[1], newTestResults()[0]},
+			expected: Expected{Rows: 2, Results: newTestResults()},
+		},
+		{
+			name:     "different results",
+			results:  changed,
+			expected: Expected{Rows: 2, Results: newTestResults()},
+			want: "returned {Count:6 Interval:2017-01-01 09:01:00 Host:host_000008 Min:15 Max:31}, " +
+				"want {Count:6 Interval:2017-01-01 09:01:00 Host:host_000008 Min:15 Max:30}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Compare(tt.results, tt.expected))
+		})
+	}
+}
+
+type testSource map[string]int
+
+func (s testSource) Expected(_ context.Context, query Query) (Expected, bool, error) {
+	if query.Host == "error" {
+		return Expected{}, false, errors.New("some error")
+	}
+	rows, ok := s[query.Host]
+	return Expected{Rows: rows}, ok, nil
+}
+
+func TestVerifier(t *testing.T) {
+	source := testSource{"a": 2, "b": 1}
+	v := New(context.Background(), source, 3)
+
+	for i := 0; i < 20; i++ {
+		v.Verify(i+2, Query{Host: "a"}, newTestResults())
+	}
+	for i := 0; i < 15; i++ {
+		v.Verify(i+22, Query{Host: "b", Start: strconv.Itoa(i)}, newTestResults())
+	}
+	v.Verify(37, Query{Host: "c"}, nil)
+	v.Verify(38, Query{Host: "error"}, nil)
+
+	summary := v.Close()
+	assert.Equal(t, summary, v.Close(), "close should be idempotent")
+	assert.Equal(t, 70, summary.RowsReturned)
+	assert.Equal(t, 20, summary.Matched)
+	assert.Equal(t, 15, summary.Mismatched)
+	assert.Equal(t, 1, summary.Unknown)
+	assert.Equal(t, 1, summary.Errors)
+	assert.Len(t, summary.Mismatches, maxMismatches)
+	for _, mismatch := range summary.Mismatches {
+		assert.Equal(t, "b", mismatch.Host)
+		assert.Equal(t, "returned 2 rows, want 1", mismatch.Reason)
+	}
+}
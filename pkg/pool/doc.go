@@ -0,0 +1,35 @@
+// Package pool provides a worker pool for load testing, which executes tasks concurrently while
+// recording their latencies.
+//
+// A pool is created with New, started with Dispatch, fed tasks with Submit and finished with Wait,
+// which returns the merged stats of all workers once every submitted task has completed or been
+// skipped:
+//
+//	p := pool.New(pool.Config[int]{MaxWorkers: 10})
+//	p.Dispatch(ctx)
+//	p.Submit(&pool.Task[int]{RouteKey: "host-1", Func: query})
+//	result := p.Wait()
+//
+// Each task returns a value of type T along with an error. Values are not retained by the pool,
+// but are delivered as a TaskResult to observers as soon as each task completes, along with the
+// worker that executed it, its start and end times and any error. Results can be observed with
+// Config.OnResult, an Observer registered with Pool.Observe, or a channel returned by
+// Pool.Subscribe, which enables live dashboards, logging every task and aborting a run early by
+// cancelling the context passed to Dispatch.
+//
+// Tasks are routed to workers by their route key. By default, all tasks with the same route key
+// are executed by the same worker, and workers are only started when a task with an unallocated
+// route key is submitted. Other routing strategies are created with NewRouter, or by implementing
+// the Router interface.
+//
+// Open-loop load is generated by setting Task.Scheduled, in which case task latency is measured
+// from the scheduled time rather than when the task started, and the delay is recorded as schedule
+// lag.
+//
+// # Stability
+//
+// The exported API of this package follows semantic versioning together with the module: exported
+// identifiers are not removed or changed incompatibly, although fields may be added to structs.
+// Config, Task and TaskResult should therefore be constructed with field names. Stats are recorded
+// in histograms from the stats package.
+package pool
@@ -0,0 +1,150 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/joshjon/tsbenchmark/pkg/pool"
+	"strconv"
+	"sync/atomic"
+)
+
+func Example() {
+	p := pool.New(pool.Config[int]{MaxWorkers: 4})
+	p.Dispatch(context.Background())
+
+	for i := 0; i < 100; i++ {
+		i := i
+		p.Submit(&pool.Task[int]{
+			RouteKey: "host-" + strconv.Itoa(i%4),
+			Func: func(ctx context.Context) (int, error) {
+				return i, nil
+			},
+		})
+	}
+
+	result := p.Wait()
+	fmt.Println(result.Latencies.Count())
+	// Output: 100
+}
+
+func ExampleConfig_onResult() {
+	var sum int64
+
+	p := pool.New(pool.Config[int]{
+		MaxWorkers: 4,
+		OnResult: func(result pool.TaskResult[int]) {
+			if result.Err == nil {
+				atomic.AddInt64(&sum, int64(result.Value))
+			}
+		},
+	})
+	p.Dispatch(context.Background())
+
+	for i := 1; i <= 10; i++ {
+		i := i
+		p.Submit(&pool.Task[int]{
+			RouteKey: strconv.Itoa(i),
+			Func: func(ctx context.Context) (int, error) {
+				return i, nil
+			},
+		})
+	}
+
+	p.Wait()
+	fmt.Println(atomic.LoadInt64(&sum))
+	// Output: 55
+}
+
+func ExampleNewRouter() {
+	router, err := pool.NewRouter[string](pool.RoutingConsistentHash, 0)
+	if err != nil {
+		panic(err)
+	}
+
+	p := pool.New(pool.Config[string]{
+		MaxWorkers:    3,
+		Router:        router,
+		RouteKeyStats: true,
+	})
+	p.Dispatch(context.Background())
+
+	for _, host := range []string{"a", "b", "a", "c", "a"} {
+		host := host
+		p.Submit(&pool.Task[string]{
+			RouteKey: host,
+			Func: func(ctx context.Context) (string, error) {
+				return host, nil
+			},
+		})
+	}
+
+	result := p.Wait()
+	fmt.Println(len(result.Workers), result.RouteKeys["a"].Completed)
+	// Output: 3 3
+}
+
+func ExamplePool_Observe() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := pool.New(pool.Config[int]{MaxWorkers: 1})
+
+	// Abort the run once more than 10% of the first 20 or more tasks have failed.
+	var completed, failed int64
+	p.Observe(pool.ObserverFunc[int](func(result pool.TaskResult[int]) {
+		n := atomic.AddInt64(&completed, 1)
+		if result.Err != nil {
+			atomic.AddInt64(&failed, 1)
+		}
+		if n >= 20 && float64(atomic.LoadInt64(&failed))/float64(n) > 0.1 {
+			cancel()
+		}
+	}))
+	p.Dispatch(ctx)
+
+	for i := 0; i < 1000; i++ {
+		p.Submit(&pool.Task[int]{
+			RouteKey: "host",
+			Func: func(ctx context.Context) (int, error) {
+				return 0, errors.New("connection refused")
+			},
+		})
+	}
+
+	result := p.Wait()
+	fmt.Println(result.Interrupted, result.Skipped > 0)
+	// Output: true true
+}
+
+func ExamplePool_Subscribe() {
+	p := pool.New(pool.Config[string]{MaxWorkers: 2})
+	results := p.Subscribe(100)
+	p.Dispatch(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var n int
+		for result := range results {
+			if result.Err == nil {
+				n++
+			}
+		}
+		fmt.Println(n, "tasks completed")
+	}()
+
+	for _, host := range []string{"a", "b", "c"} {
+		host := host
+		p.Submit(&pool.Task[string]{
+			RouteKey: host,
+			Func: func(ctx context.Context) (string, error) {
+				return host, nil
+			},
+		})
+	}
+
+	p.Wait()
+	<-done
+	// Output: 3 tasks completed
+}
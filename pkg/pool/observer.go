@@ -0,0 +1,68 @@
+package pool
+
+// Observer is notified of the result of each task as soon as it completes. Observers are called by
+// the worker that executed the task, so they must be safe for concurrent use and should return
+// quickly, as the worker does not execute its next task until they return.
+type Observer[T any] interface {
+	Observe(result TaskResult[T])
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc[T any] func(result TaskResult[T])
+
+func (f ObserverFunc[T]) Observe(result TaskResult[T]) {
+	f(result)
+}
+
+// channelObserver sends each result to a subscriber channel.
+type channelObserver[T any] chan TaskResult[T]
+
+func (c channelObserver[T]) Observe(result TaskResult[T]) {
+	c <- result
+}
+
+// Observe registers an observer to be notified of the result of each task. It must be called
+// before Dispatch.
+func (p *Pool[T]) Observe(observer Observer[T]) {
+	p.observers = append(p.observers, observer)
+}
+
+// Subscribe returns a channel that receives the result of each task as soon as it completes, with
+// the given buffer size. The channel is closed by Wait once all tasks have completed. Results are
+// never dropped, so a worker blocks while the buffer is full, and the channel must be received
+// from until it is closed. Subscribe must be called before Dispatch.
+func (p *Pool[T]) Subscribe(buffer int) <-chan TaskResult[T] {
+	results := make(channelObserver[T], buffer)
+	p.Observe(results)
+	p.subscribers = append(p.subscribers, results)
+	return results
+}
+
+// notify returns the function that workers call with the result of each task, or nil if nothing
+// observes results.
+func (p *Pool[T]) notify() func(result TaskResult[T]) {
+	observers := p.observers
+	if p.config.OnResult != nil {
+		observers = append([]Observer[T]{ObserverFunc[T](p.config.OnResult)}, observers...)
+	}
+
+	switch len(observers) {
+	case 0:
+		return nil
+	case 1:
+		return observers[0].Observe
+	default:
+		return func(result TaskResult[T]) {
+			for _, observer := range observers {
+				observer.Observe(result)
+			}
+		}
+	}
+}
+
+// closeSubscribers closes the channel of each subscriber once no more results will be sent.
+func (p *Pool[T]) closeSubscribers() {
+	for _, subscriber := range p.subscribers {
+		close(subscriber)
+	}
+}
@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPool_Subscribe(t *testing.T) {
+	pool := New(Config[int]{MaxWorkers: 3})
+	results := pool.Subscribe(0)
+	pool.Dispatch(context.Background())
+
+	received := make(chan []TaskResult[int])
+	go func() {
+		var all []TaskResult[int]
+		for result := range results {
+			all = append(all, result)
+		}
+		received <- all
+	}()
+
+	for i := 0; i < 30; i++ {
+		i := i
+		pool.Submit(&Task[int]{
+			RouteKey: strconv.Itoa(i % 3),
+			Func: func(ctx context.Context) (int, error) {
+				if i%10 == 0 {
+					return i, errors.New("some error")
+				}
+				return i, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	all := <-received
+	require.Len(t, all, 30)
+
+	workerIDs := make(map[int]bool)
+	for _, workerResult := range result.Workers {
+		workerIDs[workerResult.WorkerID] = true
+	}
+
+	var sum, errs int
+	for _, taskResult := range all {
+		sum += taskResult.Value
+		if taskResult.Err != nil {
+			errs += 1
+		}
+		assert.True(t, workerIDs[taskResult.WorkerID])
+		assert.False(t, taskResult.End.Before(taskResult.Start))
+		assert.NotEmpty(t, taskResult.Task.RouteKey)
+	}
+	assert.Equal(t, 435, sum)
+	assert.Equal(t, 3, errs)
+}
+
+func TestPool_Observe(t *testing.T) {
+	var onResult, first, second int32
+
+	pool := New(Config[any]{
+		MaxWorkers: 2,
+		OnResult: func(result TaskResult[any]) {
+			atomic.AddInt32(&onResult, 1)
+		},
+	})
+	pool.Observe(ObserverFunc[any](func(result TaskResult[any]) {
+		atomic.AddInt32(&first, 1)
+	}))
+	pool.Observe(ObserverFunc[any](func(result TaskResult[any]) {
+		atomic.AddInt32(&second, 1)
+	}))
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: strconv.Itoa(i % 2),
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+	pool.Wait()
+
+	assert.Equal(t, int32(10), onResult)
+	assert.Equal(t, int32(10), first)
+	assert.Equal(t, int32(10), second)
+}
+
+func TestPool_Subscribe_closedWithoutTasks(t *testing.T) {
+	pool := New(Config[any]{MaxWorkers: 1})
+	results := pool.Subscribe(1)
+	pool.Dispatch(context.Background())
+	pool.Wait()
+
+	_, ok := <-results
+	assert.False(t, ok)
+}
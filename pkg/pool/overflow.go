@@ -0,0 +1,59 @@
+package pool
+
+import "context"
+
+// overflowBudget bounds the number of tasks held in the overflows of all workers of a pool, so that
+// work stealing keeps backpressure on Submit without a single backed up worker blocking dispatch
+// to the others.
+type overflowBudget struct {
+	slots chan struct{}
+	// freed is signalled when a slot is released.
+	freed chan struct{}
+}
+
+func newOverflowBudget(size int) *overflowBudget {
+	if size < 1 {
+		size = 1
+	}
+	return &overflowBudget{
+		slots: make(chan struct{}, size),
+		freed: make(chan struct{}, 1),
+	}
+}
+
+// tryAcquire takes a slot for a task added to an overflow, and reports whether one was free. A nil
+// budget is unbounded.
+func (b *overflowBudget) tryAcquire() bool {
+	if b == nil {
+		return true
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees the slot of a task removed from an overflow.
+func (b *overflowBudget) release() {
+	if b == nil {
+		return
+	}
+	<-b.slots
+	select {
+	case b.freed <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until a slot may have been freed since the budget was last found exhausted, and
+// returns false if ctx is cancelled first.
+func (b *overflowBudget) wait(ctx context.Context) bool {
+	select {
+	case <-b.freed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,492 @@
+package pool
+
+import (
+	"context"
+	"github.com/joshjon/tsbenchmark/pkg/stats"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Config configures a pool. Only MaxWorkers is required, and all other fields are optional.
+type Config[T any] struct {
+	MaxWorkers      int
+	WorkerQueueSize int
+	WaitQueueSize   int
+	Histogram       stats.HistogramConfig
+	// RouteKeyStats enables recording of stats per route key.
+	RouteKeyStats bool
+	// TimelineInterval enables recording of a timeline of task completions bucketed by the
+	// interval when greater than zero.
+	TimelineInterval time.Duration
+	// LiveStats enables running latency percentiles in Stats while the pool is running.
+	LiveStats bool
+	// DrainTimeout is how long in-flight tasks are given to complete once the pool is cancelled,
+	// after which their context is cancelled.
+	DrainTimeout time.Duration
+	// WarmupDuration and WarmupTasks configure a warmup phase covering tasks started within the
+	// duration of the pool dispatching, and the first tasks started up to the count. Warmup tasks
+	// are executed as normal but only recorded in the warmup result.
+	WarmupDuration time.Duration
+	WarmupTasks    int
+	// Router decides which worker each task is dispatched to. Defaults to a StickyRouter.
+	Router Router[T]
+	// WorkStealing prevents a full worker queue from blocking dispatch by holding further tasks for
+	// the worker in an overflow, from which idle workers steal tasks marked as affinity-optional.
+	// The overflows of all workers share a budget of MaxWorkers * WorkerQueueSize tasks, so that a
+	// backed up worker may borrow the queue capacity that other workers are not using. Once it is
+	// exhausted, dispatch waits until any worker catches up, so that Submit still applies
+	// backpressure.
+	WorkStealing bool
+	// IdleTimeout retires workers that wait this long for a task when greater than zero, releasing
+	// their route keys so that they may be allocated to another worker. At least MinWorkers workers
+	// (and always at least one) are kept active. Routers that route over a fixed set of workers
+	// never retire workers.
+	IdleTimeout time.Duration
+	MinWorkers  int
+	// IsTimeout classifies task errors as timeouts, which are counted in each worker result, when
+	// set.
+	IsTimeout func(err error) bool
+	// OnResult is called with the result of each executed task as soon as it completes, by the
+	// worker that executed it. It must be safe for concurrent use and should return quickly, as
+	// the worker does not execute its next task until it returns. Further observers can be
+	// registered with Observe and Subscribe.
+	OnResult func(result TaskResult[T])
+}
+
+// Result is the outcome of a pool run, which is returned by Wait once all tasks have completed or
+// been skipped.
+type Result struct {
+	Workers   []*WorkerResult
+	Latencies *stats.Histogram
+	// RouteKeys holds the stats of each route key merged across workers. It is only populated
+	// when route key stats are enabled.
+	RouteKeys map[string]*RouteKeyResult
+	// Timeline is only populated when a timeline interval is configured.
+	Timeline *stats.Timeline
+	// Interrupted is true if the pool was cancelled before all tasks were executed.
+	Interrupted bool
+	// Skipped is the number of submitted tasks that were not executed due to cancellation.
+	Skipped int64
+	// ScheduleLag records how far behind schedule tasks were started, merged across workers. It
+	// is nil unless scheduled tasks were executed.
+	ScheduleLag *stats.Histogram
+	// Warmup holds the stats of warmup tasks merged across workers. It is nil unless a warmup
+	// phase is configured.
+	Warmup *WarmupResult
+}
+
+// Pool dispatches tasks to a dynamically sized set of workers, routing tasks with the same route
+// key to the same worker by default. Tasks return a value of type T.
+type Pool[T any] struct {
+	config    Config[T]
+	waitQueue chan *Task[T]
+	taskQueue chan *Task[T]
+	workers   *Workers[T]
+	// dispatched is closed once the wait queue is closed and every task has been dispatched.
+	dispatched chan struct{}
+	timeline   *stats.Timeline
+	progress   *progress
+	warmup     *warmup
+	router     Router[T]
+	// steal is signalled when an affinity-optional task overflows, and is nil unless work stealing
+	// is enabled.
+	steal chan struct{}
+	// overflowBudget bounds the tasks held in worker overflows, and is nil unless work stealing is
+	// enabled.
+	overflowBudget *overflowBudget
+	// observers and subscribers are registered before Dispatch, which combines them into onResult.
+	observers   []Observer[T]
+	subscribers []channelObserver[T]
+	onResult    func(result TaskResult[T])
+
+	ctx         context.Context
+	taskCtx     context.Context
+	cancelTasks context.CancelFunc
+	finished    chan struct{}
+}
+
+// New creates a pool with the given config. Tasks are not dispatched until Dispatch is called.
+func New[T any](config Config[T]) *Pool[T] {
+	taskCtx, cancelTasks := context.WithCancel(context.Background())
+	p := &Pool[T]{
+		config:      config,
+		waitQueue:   make(chan *Task[T], config.WaitQueueSize),
+		taskQueue:   make(chan *Task[T]),
+		dispatched:  make(chan struct{}),
+		progress:    &progress{},
+		workers:     newWorkers[T](),
+		ctx:         context.Background(),
+		taskCtx:     taskCtx,
+		cancelTasks: cancelTasks,
+		finished:    make(chan struct{}),
+		router:      config.Router,
+	}
+	if p.router == nil {
+		p.router = NewStickyRouter[T]()
+	}
+	if config.WorkStealing {
+		p.steal = make(chan struct{}, config.MaxWorkers)
+		p.overflowBudget = newOverflowBudget(config.MaxWorkers * config.WorkerQueueSize)
+	}
+	if config.LiveStats {
+		p.progress.latencies = stats.NewHistogram(config.Histogram)
+	}
+	if config.TimelineInterval > 0 {
+		p.timeline = stats.NewTimeline(time.Now(), config.TimelineInterval, config.Histogram)
+	}
+	return p
+}
+
+// Dispatch expects tasks to be submitted to the wait queue with Submit. Tasks are received
+// from the queue and are then directed by the pool's router. If the router selects a worker, add
+// the task to the worker's queue. Otherwise, start a new worker (if not at max) and add the task
+// to the task queue so that any available worker can receive it. With the default sticky router,
+// a worker is selected if the task route key is allocated to it. It is worth noting that workers
+// are then only started when an available task with an unallocated route key is received, which
+// ensures that workers are not unnecessarily spun up. For example, a task queue of 100 tasks with
+// identical route keys must be routed to the same worker, so we only start 1 worker even if the
+// max allows for more. Routers that route over a fixed set of workers have all workers started
+// up front instead. With work stealing enabled, a backed up worker does not block dispatch of
+// tasks to other workers, and its affinity-optional tasks may be stolen by idle workers.
+//
+// When ctx is cancelled, tasks that have not started are skipped and in-flight tasks are given
+// the drain timeout to complete before the context passed to them is cancelled.
+func (p *Pool[T]) Dispatch(ctx context.Context) {
+	p.ctx = ctx
+	p.warmup = newWarmup(p.config.WarmupDuration, p.config.WarmupTasks)
+	p.onResult = p.notify()
+	go p.drain()
+
+	if p.router.FixedWorkers() {
+		for p.workers.Len() < p.config.MaxWorkers {
+			p.startWorker()
+		}
+	}
+
+	go func() {
+		for task := range p.waitQueue {
+			if ctx.Err() != nil {
+				p.progress.skip()
+				continue
+			}
+
+			if !p.dispatchWorker(task) {
+				if p.workers.Active() < p.config.MaxWorkers {
+					p.startWorker()
+				}
+				p.send(p.taskQueue, task)
+			}
+		}
+
+		close(p.taskQueue)
+		close(p.dispatched)
+		zap.L().Debug("wait queue closed, dispatch done")
+	}()
+}
+
+// dispatchWorker sends a task to the worker selected by the router, and reports whether one was
+// selected. If the overflow budget is exhausted, it waits for a slot to be freed by any worker and
+// then routes the task again, or skips the task if the pool is cancelled first.
+func (p *Pool[T]) dispatchWorker(task *Task[T]) bool {
+	for {
+		routed, sent := p.routeWorker(task)
+		if !routed || sent {
+			return routed
+		}
+		if !p.overflowBudget.wait(p.ctx) {
+			p.progress.skip()
+			return true
+		}
+	}
+}
+
+// routeWorker sends a task to the worker selected by the router, reporting whether one was selected
+// and whether the task was sent. The worker cannot retire between being selected and receiving the
+// task.
+func (p *Pool[T]) routeWorker(task *Task[T]) (routed bool, sent bool) {
+	p.workers.dispatchMu.Lock()
+	defer p.workers.dispatchMu.Unlock()
+
+	// A stopped worker can no longer receive tasks, so it is treated as if no worker was routed to.
+	worker := p.router.Route(task, p.workers)
+	if worker == nil || worker.Stopped() {
+		return false, false
+	}
+	return true, p.sendWorker(worker, task)
+}
+
+func (p *Pool[T]) startWorker() {
+	var idleTimeout time.Duration
+	if !p.router.FixedWorkers() {
+		idleTimeout = p.config.IdleTimeout
+	}
+
+	w := newWorker(workerConfig[T]{
+		ID:             p.workers.Len() + 1,
+		QueueSize:      p.config.WorkerQueueSize,
+		Histogram:      p.config.Histogram,
+		RouteKeyStats:  p.config.RouteKeyStats,
+		Timeline:       p.timeline,
+		progress:       p.progress,
+		warmup:         p.warmup,
+		taskCtx:        p.taskCtx,
+		workers:        p.workers,
+		steal:          p.steal,
+		overflowBudget: p.overflowBudget,
+		idleTimeout:    idleTimeout,
+		minWorkers:     p.config.MinWorkers,
+		OnResult:       p.onResult,
+	}, p.taskQueue)
+	w.Start(p.ctx)
+	p.workers.append(w)
+	zap.L().Debug("worker started", zap.Int("worker_count", p.workers.Active()))
+}
+
+// drain cancels the context of in-flight tasks once the drain timeout has elapsed after the
+// pool is cancelled, or when the pool has finished.
+func (p *Pool[T]) drain() {
+	defer p.cancelTasks()
+
+	select {
+	case <-p.ctx.Done():
+		zap.L().Debug("pool cancelled, draining in-flight tasks", zap.Duration("timeout", p.config.DrainTimeout))
+	case <-p.finished:
+		return
+	}
+
+	timer := time.NewTimer(p.config.DrainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		zap.L().Debug("drain timeout elapsed, cancelling in-flight tasks")
+	case <-p.finished:
+	}
+}
+
+// send sends a task to a queue, or skips it if the pool is cancelled while the queue is full.
+func (p *Pool[T]) send(queue chan<- *Task[T], task *Task[T]) {
+	select {
+	case queue <- task:
+	case <-p.ctx.Done():
+		p.progress.skip()
+	}
+}
+
+// sendWorker sends a task to a worker's queue, and reports whether it was sent. With work stealing
+// enabled it never blocks, and returns false if the overflow budget is exhausted. Idle workers are
+// signalled if an affinity-optional task overflows the queue.
+func (p *Pool[T]) sendWorker(worker *Worker[T], task *Task[T]) bool {
+	if !p.config.WorkStealing {
+		p.send(worker.workerQueue, task)
+		return true
+	}
+
+	overflowed, ok := worker.enqueue(task)
+	if !ok {
+		return false
+	}
+	if overflowed && task.AffinityOptional {
+		worker.signalSteal()
+	}
+	return true
+}
+
+// Submit adds a task to the pool's wait queue. If the pool has been cancelled, the task is
+// skipped instead.
+func (p *Pool[T]) Submit(task *Task[T]) {
+	p.progress.submit()
+	p.send(p.waitQueue, task)
+}
+
+// Stats returns a snapshot of the pool's progress. It is safe to call while the pool is running.
+func (p *Pool[T]) Stats() Stats {
+	s := p.progress.stats()
+	s.Workers = p.workers.Active()
+	return s
+}
+
+// Wait blocks until all tasks have completed or been skipped and until all worker results have
+// been received. The latencies recorded by each worker are merged into a single histogram for
+// the pool.
+//
+// Once the dispatcher has closed the task queue, each worker sends its result when its own queue
+// is empty, so receiving every worker result guarantees that all tasks have completed.
+func (p *Pool[T]) Wait() *Result {
+	close(p.waitQueue)
+	<-p.dispatched
+
+	workers := p.workers.waitAll()
+	close(p.finished)
+	p.closeSubscribers()
+
+	result := &Result{
+		Workers:     workers,
+		Latencies:   stats.NewHistogram(p.config.Histogram),
+		Timeline:    p.timeline,
+		Interrupted: p.ctx.Err() != nil,
+		Skipped:     p.progress.stats().Skipped,
+	}
+	if p.config.RouteKeyStats {
+		result.RouteKeys = make(map[string]*RouteKeyResult)
+	}
+	if p.warmup != nil {
+		result.Warmup = newWarmupResult(p.config.Histogram)
+		result.Warmup.Elapsed = p.warmup.elapsed()
+	}
+
+	for _, workerResult := range result.Workers {
+		result.Latencies.Merge(workerResult.Latencies)
+
+		if workerResult.Warmup != nil && result.Warmup != nil {
+			result.Warmup.merge(workerResult.Warmup)
+		}
+
+		if workerResult.ScheduleLag != nil {
+			if result.ScheduleLag == nil {
+				result.ScheduleLag = stats.NewHistogram(p.config.Histogram)
+			}
+			result.ScheduleLag.Merge(workerResult.ScheduleLag)
+		}
+
+		for routeKey, routeKeyResult := range workerResult.RouteKeyResults {
+			merged, ok := result.RouteKeys[routeKey]
+			if !ok {
+				merged = newRouteKeyResult(p.config.Histogram)
+				result.RouteKeys[routeKey] = merged
+			}
+			merged.merge(routeKeyResult)
+		}
+	}
+
+	return result
+}
+
+// Workers is the set of workers started by a pool, along with an index of the worker that each
+// route key is allocated to. It is safe for concurrent use.
+type Workers[T any] struct {
+	mu      sync.RWMutex
+	workers []*Worker[T]
+	// active holds the workers that have not retired. It is replaced rather than modified when a
+	// worker starts or retires, so that it can be read by routers without being copied.
+	active    []*Worker[T]
+	routeKeys map[string]*Worker[T]
+	// dispatchMu is held by the dispatcher from routing a task until it is sent to a worker, so that
+	// the worker cannot retire in between.
+	dispatchMu sync.Mutex
+}
+
+func newWorkers[T any]() *Workers[T] {
+	return &Workers[T]{routeKeys: make(map[string]*Worker[T])}
+}
+
+// List returns a copy of the workers that have not retired, in the order they were started.
+func (l *Workers[T]) List() []*Worker[T] {
+	active := l.list()
+	list := make([]*Worker[T], len(active))
+	copy(list, active)
+	return list
+}
+
+// list returns the workers that have not retired without copying them, so the returned slice must
+// not be modified.
+func (l *Workers[T]) list() []*Worker[T] {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.active
+}
+
+// Len returns the number of workers started.
+func (l *Workers[T]) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.workers)
+}
+
+// Active returns the number of workers that have not retired.
+func (l *Workers[T]) Active() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.active)
+}
+
+// ByRouteKey returns the worker that the route key is allocated to in constant time.
+func (l *Workers[T]) ByRouteKey(routeKey string) (*Worker[T], bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	worker, ok := l.routeKeys[routeKey]
+	return worker, ok
+}
+
+// allocate indexes the route key to the worker, unless it is already allocated to another worker.
+func (l *Workers[T]) allocate(routeKey string, worker *Worker[T]) {
+	l.mu.RLock()
+	_, ok := l.routeKeys[routeKey]
+	l.mu.RUnlock()
+	if ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok = l.routeKeys[routeKey]; !ok {
+		l.routeKeys[routeKey] = worker
+	}
+}
+
+func (l *Workers[T]) append(worker *Worker[T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.workers = append(l.workers, worker)
+	active := make([]*Worker[T], len(l.active), len(l.active)+1)
+	copy(active, l.active)
+	l.active = append(active, worker)
+}
+
+// retire releases the route keys of an idle worker and reports whether it has stopped, after which
+// tasks can no longer be submitted to it. The worker is kept if tasks have been queued to it or if
+// no more than min workers (at least one) are active. It is also kept if a task is being
+// dispatched or submitted, as the task may be on its way to the worker.
+func (l *Workers[T]) retire(worker *Worker[T], min int) bool {
+	if !l.dispatchMu.TryLock() {
+		return false
+	}
+	defer l.dispatchMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if min < 1 {
+		min = 1
+	}
+	if len(l.active) <= min || !worker.tryStop() {
+		return false
+	}
+
+	for _, routeKey := range worker.routeKeys.List() {
+		if key := routeKey.(string); l.routeKeys[key] == worker {
+			delete(l.routeKeys, key)
+		}
+	}
+	active := make([]*Worker[T], 0, len(l.active)-1)
+	for _, other := range l.active {
+		if other != worker {
+			active = append(active, other)
+		}
+	}
+	l.active = active
+	return true
+}
+
+// waitAll waits for the results of every worker started, including retired workers.
+func (l *Workers[T]) waitAll() []*WorkerResult {
+	l.mu.RLock()
+	workers := l.workers
+	l.mu.RUnlock()
+
+	var results []*WorkerResult
+	for _, worker := range workers {
+		results = append(results, worker.Wait())
+	}
+	return results
+}
@@ -0,0 +1,981 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_Dispatch_identicalRouteKeysOneWorkerStarted(t *testing.T) {
+	maxWorkers := 1000
+	wantWorkers := 1
+
+	pool := New(Config[any]{
+		MaxWorkers: maxWorkers,
+	})
+
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < pool.config.MaxWorkers; i++ {
+		task := &Task[any]{
+			RouteKey: "identical-route-key",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		}
+		pool.Submit(task)
+		time.Sleep(time.Millisecond)
+
+	}
+
+	results := pool.Wait()
+	assert.Len(t, pool.workers.workers, wantWorkers)
+	assert.Len(t, results.Workers, wantWorkers)
+}
+
+func TestPool_Dispatch_uniqueRouteKeysMaxWorkersStarted(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantMax int
+	}{
+		{
+			name:    "1 worker",
+			wantMax: 1,
+		},
+		{
+			name:    "10 workers",
+			wantMax: 10,
+		},
+		{
+			name:    "100 workers",
+			wantMax: 100,
+		},
+		{
+			name:    "1000 workers",
+			wantMax: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := New(Config[any]{
+				MaxWorkers: tt.wantMax,
+			})
+
+			pool.Dispatch(context.Background())
+
+			for i := 0; i < tt.wantMax; i++ {
+				task := &Task[any]{
+					RouteKey: time.Now().String(),
+					Func: func(ctx context.Context) (any, error) {
+						return nil, nil
+					},
+				}
+				pool.Submit(task)
+			}
+
+			time.Sleep(time.Millisecond)
+			results := pool.Wait()
+			assert.Len(t, pool.workers.workers, tt.wantMax)
+			assert.Len(t, results.Workers, tt.wantMax)
+			assert.Equal(t, int64(tt.wantMax), results.Latencies.Count())
+		})
+	}
+}
+
+func TestPool_Wait_routeKeyStats(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:    10,
+		RouteKeyStats: true,
+	})
+
+	pool.Dispatch(context.Background())
+
+	routeKeys := []string{"a", "b", "c", "a", "b", "a"}
+	for _, routeKey := range routeKeys {
+		task := &Task[any]{
+			RouteKey: routeKey,
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		}
+		pool.Submit(task)
+		time.Sleep(time.Millisecond)
+	}
+
+	results := pool.Wait()
+	assert.Len(t, results.RouteKeys, 3)
+	assert.Equal(t, 3, results.RouteKeys["a"].Completed)
+	assert.Equal(t, 2, results.RouteKeys["b"].Completed)
+	assert.Equal(t, 1, results.RouteKeys["c"].Completed)
+	assert.Equal(t, int64(3), results.RouteKeys["a"].Latencies.Count())
+}
+
+func TestPool_Wait_timeline(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:       10,
+		TimelineInterval: time.Second,
+	})
+
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 100; i++ {
+		task := &Task[any]{
+			RouteKey: strconv.Itoa(i % 10),
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		}
+		pool.Submit(task)
+	}
+
+	results := pool.Wait()
+	require.NotNil(t, results.Timeline)
+
+	var completed int
+	for _, bucket := range results.Timeline.Buckets() {
+		completed += bucket.Completed
+	}
+	assert.Equal(t, 100, completed)
+}
+
+func TestPool_Stats(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers: 5,
+		LiveStats:  true,
+	})
+
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 50; i++ {
+		i := i
+		task := &Task[any]{
+			RouteKey: strconv.Itoa(i % 5),
+			Func: func(ctx context.Context) (any, error) {
+				if i%10 == 0 {
+					return nil, errors.New("some error")
+				}
+				return nil, nil
+			},
+		}
+		pool.Submit(task)
+	}
+
+	pool.Wait()
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(50), stats.Submitted)
+	assert.Equal(t, int64(50), stats.Completed)
+	assert.Equal(t, int64(5), stats.Errors)
+	assert.Equal(t, int64(0), stats.BusyWorkers)
+	assert.LessOrEqual(t, stats.Workers, 5)
+	assert.LessOrEqual(t, stats.P50, stats.P99)
+}
+
+func TestPool_Dispatch_cancelDrainsInFlightTasks(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:      1,
+		WorkerQueueSize: 10,
+		WaitQueueSize:   10,
+		DrainTimeout:    time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Dispatch(ctx)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	pool.Submit(&Task[any]{
+		RouteKey: "a",
+		Func: func(ctx context.Context) (any, error) {
+			close(started)
+			<-release
+			return nil, ctx.Err()
+		},
+	})
+	for i := 0; i < 4; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "a",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	<-started
+	cancel()
+	close(release)
+
+	result := pool.Wait()
+	assert.True(t, result.Interrupted)
+	assert.Equal(t, int64(4), result.Skipped)
+	require.Len(t, result.Workers, 1)
+	assert.Equal(t, 1, result.Workers[0].Completed)
+	assert.Zero(t, result.Workers[0].Errors)
+}
+
+func TestPool_Dispatch_drainTimeoutCancelsInFlightTasks(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:   1,
+		DrainTimeout: 10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Dispatch(ctx)
+
+	started := make(chan struct{})
+	pool.Submit(&Task[any]{
+		RouteKey: "a",
+		Func: func(ctx context.Context) (any, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	<-started
+	cancel()
+
+	result := pool.Wait()
+	assert.True(t, result.Interrupted)
+	require.Len(t, result.Workers, 1)
+	assert.Equal(t, 1, result.Workers[0].Errors)
+	require.Len(t, result.Workers[0].ErrorSamples, 1)
+	assert.ErrorIs(t, result.Workers[0].ErrorSamples[0], context.Canceled)
+}
+
+func TestPool_Submit_afterCancel(t *testing.T) {
+	pool := New(Config[any]{MaxWorkers: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Dispatch(ctx)
+	cancel()
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "a",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	assert.True(t, result.Interrupted)
+	assert.Equal(t, int64(10), result.Skipped)
+	assert.Empty(t, result.Workers)
+}
+
+func TestPool_Wait_scheduleLag(t *testing.T) {
+	pool := New(Config[any]{MaxWorkers: 5})
+	pool.Dispatch(context.Background())
+
+	scheduled := time.Now()
+	for i := 0; i < 20; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey:  strconv.Itoa(i % 5),
+			Scheduled: scheduled,
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	require.NotNil(t, result.ScheduleLag)
+	assert.Equal(t, int64(20), result.ScheduleLag.Count())
+}
+
+func TestPool_Wait_warmupTasks(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:  5,
+		WarmupTasks: 10,
+	})
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 50; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: strconv.Itoa(i % 5),
+			Func: func(ctx context.Context) (any, error) {
+				return nil, errors.New("some error")
+			},
+		})
+	}
+
+	result := pool.Wait()
+	require.NotNil(t, result.Warmup)
+	assert.Equal(t, 10, result.Warmup.Completed)
+	assert.Equal(t, 10, result.Warmup.Errors)
+	assert.Equal(t, int64(10), result.Warmup.Latencies.Count())
+
+	var completed, errs int
+	for _, workerResult := range result.Workers {
+		completed += workerResult.Completed
+		errs += workerResult.Errors
+	}
+	assert.Equal(t, 40, completed)
+	assert.Equal(t, 40, errs)
+	assert.Equal(t, int64(40), result.Latencies.Count())
+}
+
+func TestPool_Wait_warmupDuration(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:     1,
+		WarmupDuration: time.Hour,
+	})
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 5; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "a",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	require.NotNil(t, result.Warmup)
+	assert.Equal(t, 5, result.Warmup.Completed)
+	assert.Equal(t, int64(0), result.Latencies.Count())
+	assert.Greater(t, int64(result.Warmup.Elapsed), int64(0), "warmup should last until the pool finished")
+}
+
+func TestPool_Wait_warmupElapsed(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:  1,
+		WarmupTasks: 2,
+	})
+	pool.Dispatch(context.Background())
+	dispatched := time.Now()
+
+	for i := 0; i < 4; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "a",
+			Func: func(ctx context.Context) (any, error) {
+				time.Sleep(10 * time.Millisecond)
+				return nil, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	elapsed := time.Since(dispatched)
+	require.NotNil(t, result.Warmup)
+	assert.GreaterOrEqual(t, result.Warmup.Elapsed, 20*time.Millisecond)
+	assert.Less(t, result.Warmup.Elapsed, elapsed-10*time.Millisecond)
+}
+
+func TestPool_Wait_noWarmup(t *testing.T) {
+	pool := New(Config[any]{MaxWorkers: 1})
+	pool.Dispatch(context.Background())
+	pool.Submit(&Task[any]{
+		RouteKey: "a",
+		Func: func(ctx context.Context) (any, error) {
+			return nil, nil
+		},
+	})
+
+	result := pool.Wait()
+	assert.Nil(t, result.Warmup)
+}
+
+func TestWorkers_allocate(t *testing.T) {
+	workers, list := newTestWorkers(2)
+
+	_, ok := workers.ByRouteKey("a")
+	assert.False(t, ok)
+
+	workers.allocate("a", list[0])
+	workers.allocate("a", list[1])
+	workers.allocate("b", list[1])
+
+	worker, ok := workers.ByRouteKey("a")
+	require.True(t, ok)
+	assert.Equal(t, list[0], worker)
+
+	worker, ok = workers.ByRouteKey("b")
+	require.True(t, ok)
+	assert.Equal(t, list[1], worker)
+}
+
+func TestWorkers_retire(t *testing.T) {
+	workers, list := newTestWorkers(3)
+	list[1].routeKeys.Add("a")
+	workers.allocate("a", list[1])
+
+	require.True(t, list[0].Submit(&Task[any]{}))
+	assert.False(t, workers.retire(list[0], 1), "worker with queued tasks should not retire")
+
+	require.True(t, workers.retire(list[1], 1))
+	assert.True(t, list[1].Stopped())
+	assert.False(t, list[1].Submit(&Task[any]{}), "stopped worker should not accept tasks")
+	_, ok := workers.ByRouteKey("a")
+	assert.False(t, ok)
+
+	assert.False(t, workers.retire(list[2], 2), "min workers should be kept")
+	assert.Equal(t, 3, workers.Len())
+	assert.Equal(t, 2, workers.Active())
+	assert.Equal(t, []*Worker[any]{list[0], list[2]}, workers.List())
+}
+
+func TestWorkers_List_copy(t *testing.T) {
+	workers, list := newTestWorkers(2)
+	list[0] = nil
+	assert.NotNil(t, workers.List()[0])
+}
+
+func BenchmarkPool_Dispatch(b *testing.B) {
+	for _, workers := range []int{10, 1000, 10000} {
+		workers := workers
+		b.Run(strconv.Itoa(workers)+" workers", func(b *testing.B) {
+			routeKeys := make([]string, workers)
+			for i := range routeKeys {
+				routeKeys[i] = strconv.Itoa(i)
+			}
+
+			pool := New(Config[any]{
+				MaxWorkers:      workers,
+				WorkerQueueSize: 100,
+				WaitQueueSize:   100,
+			})
+			pool.Dispatch(context.Background())
+
+			// Allocate every route key before timing, so that dispatch is measured at full size.
+			for _, routeKey := range routeKeys {
+				pool.Submit(&Task[any]{RouteKey: routeKey, Func: func(ctx context.Context) (any, error) { return nil, nil }})
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				pool.Submit(&Task[any]{
+					RouteKey: routeKeys[i%workers],
+					Func: func(ctx context.Context) (any, error) {
+						return nil, nil
+					},
+				})
+			}
+			pool.Wait()
+		})
+	}
+}
+
+func BenchmarkStickyRouter_Route(b *testing.B) {
+	for _, n := range []int{10, 1000, 10000} {
+		n := n
+		b.Run(strconv.Itoa(n)+" workers", func(b *testing.B) {
+			workers, list := newTestWorkers(n)
+			for i, worker := range list {
+				workers.allocate(strconv.Itoa(i), worker)
+			}
+
+			router := NewStickyRouter[any]()
+			task := &Task[any]{RouteKey: strconv.Itoa(n - 1)}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				router.Route(task, workers)
+			}
+		})
+	}
+}
+
+func TestPool_concurrentUse(t *testing.T) {
+	tests := []struct {
+		name         string
+		routing      string
+		workers      int
+		workStealing bool
+		idleTimeout  time.Duration
+	}{
+		{name: "sticky 1000 workers", routing: RoutingSticky, workers: 1000},
+		{name: "hash 1000 workers", routing: RoutingConsistentHash, workers: 1000},
+		{name: "least-loaded 1000 workers", routing: RoutingLeastLoaded, workers: 1000},
+		{name: "work stealing 1000 workers", routing: RoutingSticky, workers: 1000, workStealing: true},
+		{name: "idle timeout 1000 workers", routing: RoutingSticky, workers: 1000, idleTimeout: time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := NewRouter[any](tt.routing, 1)
+			require.NoError(t, err)
+
+			pool := New(Config[any]{
+				MaxWorkers:       tt.workers,
+				WorkerQueueSize:  10,
+				WaitQueueSize:    10,
+				RouteKeyStats:    true,
+				TimelineInterval: time.Second,
+				LiveStats:        true,
+				WarmupTasks:      100,
+				Router:           router,
+				WorkStealing:     tt.workStealing,
+				IdleTimeout:      tt.idleTimeout,
+				MinWorkers:       10,
+			})
+			pool.Dispatch(context.Background())
+
+			stop := make(chan struct{})
+			statsDone := make(chan struct{})
+			go func() {
+				defer close(statsDone)
+				for {
+					select {
+					case <-stop:
+						return
+					case <-time.After(time.Millisecond):
+						pool.Stats()
+					}
+				}
+			}()
+
+			submitters, tasks := 8, 500
+			var wg sync.WaitGroup
+			for s := 0; s < submitters; s++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < tasks; i++ {
+						pool.Submit(&Task[any]{
+							RouteKey:         strconv.Itoa(i % (tt.workers * 2)),
+							Scheduled:        time.Now(),
+							AffinityOptional: i%2 == 0,
+							Func: func(ctx context.Context) (any, error) {
+								return nil, nil
+							},
+						})
+					}
+				}()
+			}
+			wg.Wait()
+
+			result := pool.Wait()
+			close(stop)
+			<-statsDone
+
+			var completed int
+			for _, workerResult := range result.Workers {
+				completed += workerResult.Completed
+			}
+			assert.Equal(t, submitters*tasks-100, completed)
+			assert.Equal(t, 100, result.Warmup.Completed)
+			assert.Equal(t, int64(submitters*tasks), pool.Stats().Completed)
+			if tt.idleTimeout == 0 {
+				assert.LessOrEqual(t, len(result.Workers), tt.workers)
+			}
+		})
+	}
+}
+
+func TestPool_Dispatch_workStealingFullQueueDoesNotBlock(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:      2,
+		WorkerQueueSize: 5,
+		WaitQueueSize:   1,
+		WorkStealing:    true,
+	})
+	pool.Dispatch(context.Background())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit(&Task[any]{
+		RouteKey: "a",
+		Func: func(ctx context.Context) (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		},
+	})
+	<-started
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "a",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	done := make(chan struct{})
+	pool.Submit(&Task[any]{
+		RouteKey: "b",
+		Func: func(ctx context.Context) (any, error) {
+			close(done)
+			return nil, nil
+		},
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task for another route key was blocked by a full worker queue")
+	}
+	close(release)
+
+	result := pool.Wait()
+	require.Len(t, result.Workers, 2)
+	assert.Equal(t, 11, result.Workers[0].Completed)
+	assert.Equal(t, 1, result.Workers[1].Completed)
+}
+
+func TestPool_Submit_workStealingFullOverflowBlocks(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:      2,
+		WorkerQueueSize: 1,
+		WaitQueueSize:   2,
+		WorkStealing:    true,
+	})
+	pool.Dispatch(context.Background())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit(&Task[any]{
+		RouteKey: "a",
+		Func: func(ctx context.Context) (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		},
+	})
+	<-started
+
+	// Tasks that cannot be stolen fill the worker queue (1) and the overflow budget of both worker
+	// queues (2), then one is held by the waiting dispatcher and the wait queue (2) fills, after
+	// which Submit must block.
+	var submitted int32
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.Submit(&Task[any]{
+				RouteKey: "a",
+				Func: func(ctx context.Context) (any, error) {
+					return nil, nil
+				},
+			})
+			atomic.AddInt32(&submitted, 1)
+		}
+	}()
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&submitted) == 6
+	}, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(6), atomic.LoadInt32(&submitted), "submit should block once the overflow is full")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&submitted) == 10
+	}, 5*time.Second, time.Millisecond)
+
+	result := pool.Wait()
+	require.Len(t, result.Workers, 1)
+	assert.Equal(t, 11, result.Workers[0].Completed)
+}
+
+func TestPool_Dispatch_workStealingStuckRouteKeyDoesNotBlockOthers(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:      2,
+		WorkerQueueSize: 2,
+		WaitQueueSize:   1,
+		WorkStealing:    true,
+	})
+	pool.Dispatch(context.Background())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit(&Task[any]{
+		RouteKey: "stuck",
+		Func: func(ctx context.Context) (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		},
+	})
+	<-started
+
+	// The stuck worker's queue (2) fills and the rest of its tasks overflow beyond the wait queue
+	// size (1), borrowing from the overflow budget of both worker queues (4).
+	for i := 0; i < 5; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "stuck",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	var healthy int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			pool.Submit(&Task[any]{
+				RouteKey: "healthy",
+				Func: func(ctx context.Context) (any, error) {
+					atomic.AddInt32(&healthy, 1)
+					return nil, nil
+				},
+			})
+		}
+	}()
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&healthy) == 50
+	}, 5*time.Second, time.Millisecond, "tasks for a healthy route key were blocked by a stuck one")
+	close(release)
+	<-done
+
+	result := pool.Wait()
+	require.Len(t, result.Workers, 2)
+	assert.Equal(t, 6, result.Workers[0].Completed)
+	assert.Equal(t, 50, result.Workers[1].Completed)
+}
+
+func TestPool_Dispatch_workStealing(t *testing.T) {
+	tests := []struct {
+		name             string
+		affinityOptional bool
+		wantStolen       int
+	}{
+		{
+			name:             "affinity optional tasks are stolen",
+			affinityOptional: true,
+			wantStolen:       4,
+		},
+		{
+			name:             "affinity required tasks are not stolen",
+			affinityOptional: false,
+			wantStolen:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := New(Config[any]{
+				MaxWorkers:      2,
+				WorkerQueueSize: 1,
+				WaitQueueSize:   5,
+				WorkStealing:    true,
+				RouteKeyStats:   true,
+			})
+			pool.Dispatch(context.Background())
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			pool.Submit(&Task[any]{
+				RouteKey: "a",
+				Func: func(ctx context.Context) (any, error) {
+					close(started)
+					<-release
+					return nil, nil
+				},
+			})
+			<-started
+
+			// Start a second worker that is idle once its task completes.
+			done := make(chan struct{})
+			pool.Submit(&Task[any]{
+				RouteKey: "b",
+				Func: func(ctx context.Context) (any, error) {
+					close(done)
+					return nil, nil
+				},
+			})
+			<-done
+
+			// One task fills the worker queue of the blocked worker and the rest overflow.
+			var completed int32
+			for i := 0; i < 5; i++ {
+				pool.Submit(&Task[any]{
+					RouteKey:         "a",
+					AffinityOptional: tt.affinityOptional,
+					Func: func(ctx context.Context) (any, error) {
+						atomic.AddInt32(&completed, 1)
+						return nil, nil
+					},
+				})
+			}
+
+			if tt.wantStolen > 0 {
+				assert.Eventually(t, func() bool {
+					return atomic.LoadInt32(&completed) == int32(tt.wantStolen)
+				}, 5*time.Second, time.Millisecond)
+			} else {
+				time.Sleep(10 * time.Millisecond)
+				assert.Equal(t, int32(0), atomic.LoadInt32(&completed))
+			}
+			close(release)
+
+			result := pool.Wait()
+			require.Len(t, result.Workers, 2)
+			assert.Equal(t, 6-tt.wantStolen, result.Workers[0].Completed)
+			assert.Equal(t, 1+tt.wantStolen, result.Workers[1].Completed)
+			assert.Equal(t, 6, result.RouteKeys["a"].Completed)
+			assert.Equal(t, 1, result.Workers[1].RouteKeys)
+		})
+	}
+}
+
+func TestPool_Dispatch_idleTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		idleTimeout time.Duration
+		minWorkers  int
+		wantActive  int
+	}{
+		{
+			name:       "no idle timeout",
+			wantActive: 3,
+		},
+		{
+			name:        "retired down to min workers",
+			idleTimeout: 10 * time.Millisecond,
+			minWorkers:  2,
+			wantActive:  2,
+		},
+		{
+			name:        "retired down to one worker",
+			idleTimeout: 10 * time.Millisecond,
+			wantActive:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := New(Config[any]{
+				MaxWorkers:  3,
+				IdleTimeout: tt.idleTimeout,
+				MinWorkers:  tt.minWorkers,
+			})
+			pool.Dispatch(context.Background())
+
+			// Block each task until all workers are started, so that each route key is allocated
+			// to its own worker.
+			release := make(chan struct{})
+			for _, routeKey := range []string{"a", "b", "c"} {
+				pool.Submit(&Task[any]{
+					RouteKey: routeKey,
+					Func: func(ctx context.Context) (any, error) {
+						<-release
+						return nil, nil
+					},
+				})
+			}
+			require.Eventually(t, func() bool {
+				return pool.Stats().BusyWorkers == 3
+			}, 5*time.Second, time.Millisecond)
+			close(release)
+
+			if tt.idleTimeout > 0 {
+				require.Eventually(t, func() bool {
+					return pool.Stats().Workers == tt.wantActive
+				}, 5*time.Second, time.Millisecond)
+
+				var released int
+				for _, routeKey := range []string{"a", "b", "c"} {
+					if _, ok := pool.workers.ByRouteKey(routeKey); !ok {
+						released += 1
+					}
+				}
+				assert.Equal(t, 3-tt.wantActive, released)
+			} else {
+				time.Sleep(10 * time.Millisecond)
+				assert.Equal(t, tt.wantActive, pool.Stats().Workers)
+			}
+
+			// Tasks for released route keys are still executed.
+			for _, routeKey := range []string{"a", "b", "c"} {
+				pool.Submit(&Task[any]{
+					RouteKey: routeKey,
+					Func: func(ctx context.Context) (any, error) {
+						return nil, nil
+					},
+				})
+			}
+
+			result := pool.Wait()
+			var completed, retired int
+			for _, workerResult := range result.Workers {
+				completed += workerResult.Completed
+				if workerResult.Retired {
+					retired += 1
+				}
+			}
+			assert.Equal(t, 6, completed)
+			// Workers started for released route keys may also be retired before the pool is done.
+			assert.GreaterOrEqual(t, retired, 3-tt.wantActive)
+		})
+	}
+}
+
+func TestPool_Dispatch_idleTimeoutFixedWorkers(t *testing.T) {
+	pool := New(Config[any]{
+		MaxWorkers:  3,
+		IdleTimeout: time.Millisecond,
+		Router:      NewRoundRobinRouter[any](),
+	})
+	pool.Dispatch(context.Background())
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 3, pool.Stats().Workers)
+
+	result := pool.Wait()
+	for _, workerResult := range result.Workers {
+		assert.False(t, workerResult.Retired)
+	}
+}
+
+func TestPool_Config_onResult(t *testing.T) {
+	var mu sync.Mutex
+	results := make(map[string]TaskResult[int])
+
+	pool := New(Config[int]{
+		MaxWorkers:  3,
+		WarmupTasks: 1,
+		OnResult: func(result TaskResult[int]) {
+			mu.Lock()
+			defer mu.Unlock()
+			results[result.Task.RouteKey] = result
+		},
+	})
+	pool.Dispatch(context.Background())
+
+	pool.Submit(&Task[int]{
+		RouteKey: "warmup",
+		Func: func(ctx context.Context) (int, error) {
+			return 0, nil
+		},
+	})
+	require.Eventually(t, func() bool {
+		return pool.Stats().Completed == 1
+	}, 5*time.Second, time.Millisecond)
+
+	pool.Submit(&Task[int]{
+		RouteKey: "a",
+		Func: func(ctx context.Context) (int, error) {
+			return 1, nil
+		},
+	})
+	pool.Submit(&Task[int]{
+		RouteKey: "b",
+		Func: func(ctx context.Context) (int, error) {
+			return 2, errors.New("some error")
+		},
+	})
+	pool.Wait()
+
+	require.Len(t, results, 3)
+	assert.True(t, results["warmup"].Warmup)
+	assert.Equal(t, 1, results["a"].Value)
+	assert.NoError(t, results["a"].Err)
+	assert.False(t, results["a"].Warmup)
+	assert.Equal(t, 2, results["b"].Value)
+	assert.EqualError(t, results["b"].Err, "some error")
+}
@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"github.com/joshjon/tsbenchmark/pkg/stats"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point in time snapshot of a pool's progress.
+type Stats struct {
+	Submitted int64
+	Completed int64
+	Errors    int64
+	Skipped   int64
+	// Workers is the number of workers running, excluding retired workers.
+	Workers     int
+	BusyWorkers int64
+	// ScheduleLag is how far behind schedule the most recently started scheduled task was.
+	ScheduleLag time.Duration
+	// P50 and P99 are the running task latency percentiles, which are only populated when live
+	// stats are enabled.
+	P50 time.Duration
+	P99 time.Duration
+}
+
+// progress tracks task counts across all workers as they happen, as opposed to worker results
+// which are only available once the pool is done. Counters are updated atomically so that they
+// can be read while workers are running.
+type progress struct {
+	submitted   int64
+	completed   int64
+	errors      int64
+	skipped     int64
+	busyWorkers int64
+	scheduleLag int64
+
+	// latencies is nil unless live stats are enabled.
+	mu        sync.Mutex
+	latencies *stats.Histogram
+}
+
+func (p *progress) submit() {
+	atomic.AddInt64(&p.submitted, 1)
+}
+
+func (p *progress) skip() {
+	atomic.AddInt64(&p.skipped, 1)
+}
+
+func (p *progress) lag(lag time.Duration) {
+	atomic.StoreInt64(&p.scheduleLag, int64(lag))
+}
+
+func (p *progress) start() {
+	atomic.AddInt64(&p.busyWorkers, 1)
+}
+
+func (p *progress) complete(duration time.Duration, err error) {
+	atomic.AddInt64(&p.busyWorkers, -1)
+	atomic.AddInt64(&p.completed, 1)
+	if err != nil {
+		atomic.AddInt64(&p.errors, 1)
+	}
+
+	if p.latencies != nil {
+		p.mu.Lock()
+		p.latencies.Record(duration)
+		p.mu.Unlock()
+	}
+}
+
+func (p *progress) stats() Stats {
+	s := Stats{
+		Submitted:   atomic.LoadInt64(&p.submitted),
+		Completed:   atomic.LoadInt64(&p.completed),
+		Errors:      atomic.LoadInt64(&p.errors),
+		Skipped:     atomic.LoadInt64(&p.skipped),
+		BusyWorkers: atomic.LoadInt64(&p.busyWorkers),
+		ScheduleLag: time.Duration(atomic.LoadInt64(&p.scheduleLag)),
+	}
+
+	if p.latencies != nil {
+		p.mu.Lock()
+		s.P50 = p.latencies.Percentile(50)
+		s.P99 = p.latencies.Percentile(99)
+		p.mu.Unlock()
+	}
+
+	return s
+}
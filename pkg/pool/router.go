@@ -0,0 +1,201 @@
+package pool
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// Routing strategies that can be created with NewRouter.
+const (
+	RoutingSticky         = "sticky"
+	RoutingConsistentHash = "hash"
+	RoutingRoundRobin     = "round-robin"
+	RoutingLeastLoaded    = "least-loaded"
+	RoutingRandom         = "random"
+)
+
+// hashReplicas is the number of points each worker has on the consistent hash ring, which evens
+// out the distribution of route keys between workers.
+const hashReplicas = 100
+
+// Router decides which worker a task is dispatched to. Routers are only called from the pool's
+// dispatcher goroutine, so they do not need to be safe for concurrent use.
+type Router[T any] interface {
+	// FixedWorkers reports whether tasks are routed over a fixed set of workers, in which case
+	// the pool starts all of its workers before dispatching.
+	FixedWorkers() bool
+	// Route returns the worker to submit the task to. If nil or a stopped worker is returned, the
+	// task is sent to the shared task queue to be received by the next available worker, and a
+	// new worker is started if the pool is below its max workers.
+	Route(task *Task[T], workers *Workers[T]) *Worker[T]
+}
+
+// NewRouter creates a router for the given routing strategy. The seed is only used by the random
+// router.
+func NewRouter[T any](routing string, seed int64) (Router[T], error) {
+	switch routing {
+	case RoutingSticky:
+		return NewStickyRouter[T](), nil
+	case RoutingConsistentHash:
+		return NewConsistentHashRouter[T](), nil
+	case RoutingRoundRobin:
+		return NewRoundRobinRouter[T](), nil
+	case RoutingLeastLoaded:
+		return NewLeastLoadedRouter[T](), nil
+	case RoutingRandom:
+		return NewRandomRouter[T](seed), nil
+	default:
+		return nil, fmt.Errorf("unknown routing strategy: %s", routing)
+	}
+}
+
+// StickyRouter routes tasks to the worker that has already been allocated their route key, so all
+// tasks with the same route key are executed by the same worker. Tasks with an unallocated route
+// key are received by the first available worker, which is then allocated the route key. Lookups
+// use the route key index of the workers, so routing cost is constant regardless of the number of
+// workers.
+type StickyRouter[T any] struct{}
+
+func NewStickyRouter[T any]() *StickyRouter[T] {
+	return &StickyRouter[T]{}
+}
+
+func (r *StickyRouter[T]) FixedWorkers() bool {
+	return false
+}
+
+func (r *StickyRouter[T]) Route(task *Task[T], workers *Workers[T]) *Worker[T] {
+	if worker, ok := workers.ByRouteKey(task.RouteKey); ok {
+		return worker
+	}
+	return nil
+}
+
+// ConsistentHashRouter routes tasks by hashing their route key onto a ring of workers, so all
+// tasks with the same route key are executed by the same worker without any allocation state.
+type ConsistentHashRouter[T any] struct {
+	ring    []uint32
+	owners  map[uint32]int
+	workers int
+}
+
+func NewConsistentHashRouter[T any]() *ConsistentHashRouter[T] {
+	return &ConsistentHashRouter[T]{}
+}
+
+func (r *ConsistentHashRouter[T]) FixedWorkers() bool {
+	return true
+}
+
+func (r *ConsistentHashRouter[T]) Route(task *Task[T], all *Workers[T]) *Worker[T] {
+	workers := all.list()
+	if len(workers) == 0 {
+		return nil
+	}
+	if len(workers) != r.workers {
+		r.build(len(workers))
+	}
+
+	h := hash(task.RouteKey)
+	i := sort.Search(len(r.ring), func(i int) bool { return r.ring[i] >= h })
+	if i == len(r.ring) {
+		i = 0
+	}
+	return workers[r.owners[r.ring[i]]]
+}
+
+// build places hashReplicas points on the ring for each worker.
+func (r *ConsistentHashRouter[T]) build(workers int) {
+	r.workers = workers
+	r.ring = make([]uint32, 0, workers*hashReplicas)
+	r.owners = make(map[uint32]int, workers*hashReplicas)
+
+	for w := 0; w < workers; w++ {
+		for replica := 0; replica < hashReplicas; replica++ {
+			h := hash(strconv.Itoa(w) + "-" + strconv.Itoa(replica))
+			if _, ok := r.owners[h]; ok {
+				continue
+			}
+			r.owners[h] = w
+			r.ring = append(r.ring, h)
+		}
+	}
+
+	sort.Slice(r.ring, func(i, j int) bool { return r.ring[i] < r.ring[j] })
+}
+
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// RoundRobinRouter routes tasks to each worker in turn, regardless of their route key.
+type RoundRobinRouter[T any] struct {
+	next int
+}
+
+func NewRoundRobinRouter[T any]() *RoundRobinRouter[T] {
+	return &RoundRobinRouter[T]{}
+}
+
+func (r *RoundRobinRouter[T]) FixedWorkers() bool {
+	return true
+}
+
+func (r *RoundRobinRouter[T]) Route(_ *Task[T], all *Workers[T]) *Worker[T] {
+	workers := all.list()
+	if len(workers) == 0 {
+		return nil
+	}
+	worker := workers[r.next%len(workers)]
+	r.next++
+	return worker
+}
+
+// LeastLoadedRouter routes tasks to the worker with the fewest queued and executing tasks,
+// regardless of their route key.
+type LeastLoadedRouter[T any] struct{}
+
+func NewLeastLoadedRouter[T any]() *LeastLoadedRouter[T] {
+	return &LeastLoadedRouter[T]{}
+}
+
+func (r *LeastLoadedRouter[T]) FixedWorkers() bool {
+	return true
+}
+
+func (r *LeastLoadedRouter[T]) Route(_ *Task[T], workers *Workers[T]) *Worker[T] {
+	var least *Worker[T]
+	var leastLoad int
+	for _, worker := range workers.list() {
+		if load := worker.Load(); least == nil || load < leastLoad {
+			least, leastLoad = worker, load
+		}
+	}
+	return least
+}
+
+// RandomRouter routes tasks to a random worker, regardless of their route key.
+type RandomRouter[T any] struct {
+	random *rand.Rand
+}
+
+func NewRandomRouter[T any](seed int64) *RandomRouter[T] {
+	return &RandomRouter[T]{random: rand.New(rand.NewSource(seed))}
+}
+
+func (r *RandomRouter[T]) FixedWorkers() bool {
+	return true
+}
+
+func (r *RandomRouter[T]) Route(_ *Task[T], all *Workers[T]) *Worker[T] {
+	workers := all.list()
+	if len(workers) == 0 {
+		return nil
+	}
+	return workers[r.random.Intn(len(workers))]
+}
@@ -0,0 +1,163 @@
+package pool
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func newTestWorkers(n int) (*Workers[any], []*Worker[any]) {
+	workers := newWorkers[any]()
+	for i := 0; i < n; i++ {
+		workers.append(newWorker(workerConfig[any]{ID: i + 1, QueueSize: 10}, nil))
+	}
+	return workers, workers.List()
+}
+
+func TestNewRouter(t *testing.T) {
+	for _, routing := range []string{RoutingSticky, RoutingConsistentHash, RoutingRoundRobin, RoutingLeastLoaded, RoutingRandom} {
+		router, err := NewRouter[any](routing, 0)
+		require.NoError(t, err)
+		assert.NotNil(t, router)
+	}
+
+	_, err := NewRouter[any]("unknown", 0)
+	assert.EqualError(t, err, "unknown routing strategy: unknown")
+}
+
+func TestStickyRouter(t *testing.T) {
+	workers, list := newTestWorkers(3)
+	workers.allocate("a", list[1])
+	workers.allocate("a", list[2])
+
+	router := NewStickyRouter[any]()
+	assert.False(t, router.FixedWorkers())
+	assert.Equal(t, list[1], router.Route(&Task[any]{RouteKey: "a"}, workers))
+	assert.Nil(t, router.Route(&Task[any]{RouteKey: "b"}, workers))
+}
+
+func TestConsistentHashRouter(t *testing.T) {
+	workers, list := newTestWorkers(10)
+	router := NewConsistentHashRouter[any]()
+	assert.True(t, router.FixedWorkers())
+
+	counts := make(map[*Worker[any]]int)
+	for i := 0; i < 1000; i++ {
+		task := &Task[any]{RouteKey: "host_" + strconv.Itoa(i)}
+		worker := router.Route(task, workers)
+		require.NotNil(t, worker)
+		assert.Equal(t, worker, router.Route(task, workers), "route key should always route to the same worker")
+		counts[worker]++
+	}
+
+	assert.Len(t, counts, len(list))
+	assert.Nil(t, router.Route(&Task[any]{}, newWorkers[any]()))
+}
+
+func TestRoundRobinRouter(t *testing.T) {
+	workers, list := newTestWorkers(3)
+	router := NewRoundRobinRouter[any]()
+	assert.True(t, router.FixedWorkers())
+
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, list[i%3], router.Route(&Task[any]{RouteKey: "a"}, workers))
+	}
+}
+
+func TestLeastLoadedRouter(t *testing.T) {
+	workers, list := newTestWorkers(3)
+	list[0].Submit(&Task[any]{})
+	list[0].Submit(&Task[any]{})
+	list[2].Submit(&Task[any]{})
+
+	router := NewLeastLoadedRouter[any]()
+	assert.True(t, router.FixedWorkers())
+	assert.Equal(t, list[1], router.Route(&Task[any]{}, workers))
+
+	list[1].Submit(&Task[any]{})
+	list[1].Submit(&Task[any]{})
+	assert.Equal(t, list[2], router.Route(&Task[any]{}, workers))
+}
+
+func TestRandomRouter(t *testing.T) {
+	workers, list := newTestWorkers(5)
+	a := NewRandomRouter[any](1)
+	b := NewRandomRouter[any](1)
+	assert.True(t, a.FixedWorkers())
+
+	for i := 0; i < 20; i++ {
+		worker := a.Route(&Task[any]{}, workers)
+		assert.Contains(t, list, worker)
+		assert.Equal(t, worker, b.Route(&Task[any]{}, workers))
+	}
+}
+
+func TestPool_Dispatch_fixedWorkerRouter(t *testing.T) {
+	maxWorkers := 5
+
+	pool := New(Config[any]{
+		MaxWorkers:      maxWorkers,
+		WorkerQueueSize: 10,
+		Router:          NewRoundRobinRouter[any](),
+	})
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 50; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: "identical-route-key",
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	require.Len(t, result.Workers, maxWorkers)
+	for _, workerResult := range result.Workers {
+		assert.Equal(t, 10, workerResult.Completed)
+		assert.Equal(t, 1, workerResult.RouteKeys)
+	}
+}
+
+// stoppedRouter routes every task to a worker that has stopped.
+type stoppedRouter struct {
+	worker *Worker[any]
+}
+
+func (r *stoppedRouter) FixedWorkers() bool {
+	return false
+}
+
+func (r *stoppedRouter) Route(_ *Task[any], _ *Workers[any]) *Worker[any] {
+	return r.worker
+}
+
+func TestPool_Dispatch_routerReturnsStoppedWorker(t *testing.T) {
+	_, list := newTestWorkers(1)
+	require.True(t, list[0].tryStop())
+
+	pool := New(Config[any]{
+		MaxWorkers:      2,
+		WorkerQueueSize: 10,
+		Router:          &stoppedRouter{worker: list[0]},
+	})
+	pool.Dispatch(context.Background())
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(&Task[any]{
+			RouteKey: strconv.Itoa(i),
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	result := pool.Wait()
+	var completed int
+	for _, workerResult := range result.Workers {
+		completed += workerResult.Completed
+	}
+	assert.Equal(t, 10, completed, "tasks routed to a stopped worker should be sent to the task queue")
+}
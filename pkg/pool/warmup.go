@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// warmup decides which tasks are executed during the warmup phase of a pool, which covers the
+// first tasks started up to a count, and all tasks started within a duration of the pool starting.
+type warmup struct {
+	start    time.Time
+	duration time.Duration
+	tasks    int64
+	started  int64
+	// ended is when the first task after the warmup phase started, in Unix nanoseconds.
+	ended int64
+}
+
+func newWarmup(duration time.Duration, tasks int) *warmup {
+	if duration <= 0 && tasks <= 0 {
+		return nil
+	}
+	return &warmup{
+		start:    time.Now(),
+		duration: duration,
+		tasks:    int64(tasks),
+	}
+}
+
+// next reports whether the next task to start is a warmup task. A nil warmup has no warmup phase.
+func (w *warmup) next() bool {
+	if w == nil {
+		return false
+	}
+	if atomic.AddInt64(&w.started, 1) <= w.tasks {
+		return true
+	}
+	if time.Since(w.start) < w.duration {
+		return true
+	}
+	atomic.CompareAndSwapInt64(&w.ended, 0, time.Now().UnixNano())
+	return false
+}
+
+// elapsed returns how long the warmup phase lasted, which is until the first task after it
+// started, or until now if it has not ended.
+func (w *warmup) elapsed() time.Duration {
+	if ended := atomic.LoadInt64(&w.ended); ended != 0 {
+		return time.Unix(0, ended).Sub(w.start)
+	}
+	return time.Since(w.start)
+}
@@ -0,0 +1,584 @@
+package pool
+
+import (
+	"context"
+	"github.com/fatih/set"
+	"github.com/joshjon/tsbenchmark/pkg/stats"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxErrorSamples is the max number of distinct errors kept in each worker result.
+const maxErrorSamples = 10
+
+// Task is a unit of work executed by a worker of a pool, which returns a value of type T.
+type Task[T any] struct {
+	// RouteKey identifies related tasks, such as tasks for the same host, which routers may use
+	// to execute them on the same worker.
+	RouteKey string
+	// Scheduled is the intended start time of an open-loop task. When set, task latency is
+	// measured from the scheduled time rather than the actual start time, so that time spent
+	// queued behind schedule is included, and the delay in starting is recorded as schedule lag.
+	Scheduled time.Time
+	// AffinityOptional allows the task to be stolen by an idle worker when work stealing is
+	// enabled, rather than only being executed by the worker its route key is allocated to.
+	AffinityOptional bool
+	// Func is passed a context that is cancelled when the pool is cancelled and the drain
+	// timeout has elapsed.
+	Func func(ctx context.Context) (T, error)
+}
+
+// TaskResult is the outcome of a single executed task, which is delivered to observers as soon as
+// the task completes.
+type TaskResult[T any] struct {
+	Task *Task[T]
+	// WorkerID is the ID of the worker that executed the task.
+	WorkerID int
+	// Start and End are when the task function was called and returned.
+	Start time.Time
+	End   time.Time
+	Value T
+	Err   error
+	// Warmup is true if the task was executed during the warmup phase, in which case it is
+	// excluded from all other results.
+	Warmup bool
+}
+
+// WorkerResult holds the stats of all tasks executed by a single worker.
+type WorkerResult struct {
+	WorkerID      int
+	RouteKeys     int
+	Started       time.Time
+	Stopped       time.Time
+	Completed     int
+	TotalDuration time.Duration
+	Latencies     *stats.Histogram
+	Errors        int
+	// Timeouts is the number of errors that the pool's IsTimeout func classified as timeouts.
+	Timeouts int
+	// ErrorSamples holds up to maxErrorSamples errors with distinct messages in the order they
+	// first occurred, so that errors are kept in constant memory however many tasks fail.
+	ErrorSamples []error
+	// Skipped is the number of tasks received after the worker context was cancelled.
+	Skipped int
+	// ScheduleLag records how long after their scheduled time tasks were started. It is nil
+	// unless scheduled tasks were executed.
+	ScheduleLag *stats.Histogram
+	// Warmup holds the stats of tasks executed during the warmup phase, which are excluded from
+	// all other results. It is nil unless warmup tasks were executed.
+	Warmup *WarmupResult
+	// Retired is true if the worker stopped after being idle for the idle timeout, rather than
+	// when the task queue was closed.
+	Retired bool
+	// RouteKeyResults is only populated when route key stats are enabled.
+	RouteKeyResults map[string]*RouteKeyResult
+}
+
+// RouteKeyResult holds the stats of all tasks executed for a single route key.
+type RouteKeyResult struct {
+	Completed int
+	Errors    int
+	Latencies *stats.Histogram
+}
+
+// WarmupResult holds the stats of tasks executed during the warmup phase of a pool.
+type WarmupResult struct {
+	Completed     int
+	Errors        int
+	TotalDuration time.Duration
+	Latencies     *stats.Histogram
+	// Elapsed is how long the warmup phase lasted from the pool dispatching until the first task
+	// after it started. It is only set on the pool result.
+	Elapsed time.Duration
+}
+
+// workerConfig configures a worker from the config of the pool that starts it.
+type workerConfig[T any] struct {
+	ID            int
+	QueueSize     int
+	Histogram     stats.HistogramConfig
+	RouteKeyStats bool
+	// Timeline is shared between workers and records task completions when set.
+	Timeline *stats.Timeline
+	// OnResult is called with the result of each executed task when set.
+	OnResult func(result TaskResult[T])
+	// progress is shared between the workers of a pool and tracks tasks as they complete.
+	progress *progress
+	// warmup is shared between the workers of a pool and decides which tasks are warmup tasks.
+	warmup *warmup
+	// workers indexes the route keys allocated to the worker when set.
+	workers *Workers[T]
+	// isTimeout classifies task errors as timeouts when set.
+	isTimeout func(err error) bool
+	// steal is shared between the workers of a pool and is signalled when an affinity-optional task
+	// is added to the overflow of a worker. It is nil unless work stealing is enabled.
+	steal chan struct{}
+	// overflowBudget is shared between the workers of a pool and bounds the number of tasks held in
+	// their overflows. The overflow is unbounded when it is nil.
+	overflowBudget *overflowBudget
+	// idleTimeout retires the worker once it has waited this long for a task, provided more than
+	// minWorkers workers are active. Retirement requires workers to be set.
+	idleTimeout time.Duration
+	minWorkers  int
+	// taskCtx is passed to tasks instead of the worker context when set, which allows
+	// in-flight tasks to outlive the worker context while draining.
+	taskCtx context.Context
+}
+
+// Worker executes tasks from its own queue as first priority, and from a task queue shared with
+// other workers otherwise. Workers are only started by a pool, which routers select them from.
+type Worker[T any] struct {
+	config      workerConfig[T]
+	done        chan *WorkerResult
+	workerQueue chan *Task[T]
+	taskQueue   <-chan *Task[T]
+	// workerResult is only accessed by the worker goroutine until it is sent to done, after which
+	// it is owned by the receiver. State read while the worker is running is kept in routeKeys,
+	// busy and the shared progress instead.
+	workerResult *WorkerResult
+	routeKeys    set.Interface
+	// busy is 1 while a task is executing, which is read atomically by Load.
+	busy int32
+	// overflow holds the tasks enqueued while the worker queue is full, so that a backed up worker
+	// does not block the dispatcher. Idle workers steal affinity-optional tasks from it.
+	overflowMu sync.Mutex
+	overflow   []*Task[T]
+	// wake is signalled when a task is added to the overflow.
+	wake chan struct{}
+	// stopped is set once the worker stops, after which tasks can no longer be submitted to it.
+	// submitMu is held for reading while a task is submitted, so that the worker cannot stop with
+	// the task on its way.
+	submitMu sync.RWMutex
+	stopped  bool
+}
+
+// newWorker creates a worker that receives tasks from its own queue and the given task queue.
+func newWorker[T any](config workerConfig[T], taskQueue <-chan *Task[T]) *Worker[T] {
+	w := &Worker[T]{
+		config:      config,
+		routeKeys:   set.New(set.ThreadSafe),
+		done:        make(chan *WorkerResult, 1),
+		taskQueue:   taskQueue,
+		workerQueue: make(chan *Task[T], config.QueueSize),
+		wake:        make(chan struct{}, 1),
+		workerResult: &WorkerResult{
+			WorkerID:  config.ID,
+			Latencies: stats.NewHistogram(config.Histogram),
+		},
+	}
+	if config.RouteKeyStats {
+		w.workerResult.RouteKeyResults = make(map[string]*RouteKeyResult)
+	}
+	return w
+}
+
+// Start continuously receives tasks from the worker queue to execute as first priority,
+// followed by its overflow. If both are empty, tasks will be pulled from the task queue instead,
+// or stolen from the overflow of other workers when work stealing is enabled.
+// Finally, when the worker queue and overflow are empty and the task queue is closed, the worker
+// result is sent to the done channel to indicate completion. The worker may also retire early if
+// an idle timeout is configured and it waits that long for a task. Once ctx is cancelled, any
+// tasks that are received are skipped rather than executed.
+func (w *Worker[T]) Start(ctx context.Context) {
+	w.workerResult.Started = time.Now()
+
+	taskCtx := w.config.taskCtx
+	if taskCtx == nil {
+		taskCtx = ctx
+	}
+
+	go func() {
+		var idle *time.Timer
+		if w.config.idleTimeout > 0 {
+			idle = time.NewTimer(w.config.idleTimeout)
+			defer idle.Stop()
+		}
+
+		for {
+			// Due to the random nature of select statements, a single case is required
+			// to ensure the worker queue is prioritised over the task queue.
+			select {
+			case task := <-w.workerQueue:
+				w.receive(ctx, task)
+				w.execute(ctx, taskCtx, task)
+				continue
+			default:
+			}
+
+			if task := w.popOverflow(); task != nil {
+				w.receive(ctx, task)
+				w.execute(ctx, taskCtx, task)
+				continue
+			}
+
+			var idleC <-chan time.Time
+			if idle != nil {
+				resetTimer(idle, w.config.idleTimeout)
+				idleC = idle.C
+			}
+
+			select {
+			case task := <-w.workerQueue:
+				w.receive(ctx, task)
+				w.execute(ctx, taskCtx, task)
+				continue
+			case <-idleC:
+				if w.config.workers.retire(w, w.config.minWorkers) {
+					w.stop(true)
+					zap.L().Debug("worker idle, retired", zap.Int("worker_id", w.config.ID))
+					return
+				}
+			case <-w.wake:
+				continue
+			case <-w.config.steal:
+				// Signals are dropped once every worker has one pending, so pass the signal on
+				// after a successful steal in case more tasks can be stolen.
+				if w.steal(ctx, taskCtx) {
+					w.signalSteal()
+				}
+			case task, ok := <-w.taskQueue:
+				if !ok {
+					// The task queue is only closed once dispatch is done, so no more tasks can be
+					// queued to the worker, but tasks queued before it was closed may still remain.
+					if w.Load() > 0 || w.steal(ctx, taskCtx) || !w.tryStop() {
+						continue
+					}
+					w.stop(false)
+					zap.L().Debug("task queue closed, worker done")
+					return
+				}
+				w.receive(ctx, task)
+				w.execute(ctx, taskCtx, task)
+			}
+		}
+	}()
+}
+
+// stop sends the worker result to the done channel, which is buffered so that a retired worker
+// does not have to wait for the result to be received.
+func (w *Worker[T]) stop(retired bool) {
+	w.workerResult.Stopped = time.Now()
+	w.workerResult.RouteKeys = w.routeKeys.Size()
+	w.workerResult.Retired = retired
+	w.done <- w.workerResult
+	close(w.workerQueue)
+	close(w.done)
+}
+
+// resetTimer resets a timer that may have fired without its channel being drained.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+// Submit adds a task to the worker queue, and reports whether it was added, which it is not once
+// the worker has stopped.
+func (w *Worker[T]) Submit(task *Task[T]) bool {
+	w.submitMu.RLock()
+	defer w.submitMu.RUnlock()
+	if w.stopped {
+		return false
+	}
+	w.workerQueue <- task
+	return true
+}
+
+// Stopped reports whether the worker has stopped, either because it retired or because the pool is
+// done, after which tasks can no longer be submitted to it.
+func (w *Worker[T]) Stopped() bool {
+	w.submitMu.RLock()
+	defer w.submitMu.RUnlock()
+	return w.stopped
+}
+
+// tryStop marks the worker as stopped and reports whether it succeeded, which it does not if tasks
+// are queued to the worker or a task is being submitted to it.
+func (w *Worker[T]) tryStop() bool {
+	if !w.submitMu.TryLock() {
+		return false
+	}
+	defer w.submitMu.Unlock()
+	if w.Load() > 0 {
+		return false
+	}
+	w.stopped = true
+	return true
+}
+
+// Load returns the number of tasks queued and executing on the worker.
+func (w *Worker[T]) Load() int {
+	w.overflowMu.Lock()
+	overflow := len(w.overflow)
+	w.overflowMu.Unlock()
+	return len(w.workerQueue) + overflow + int(atomic.LoadInt32(&w.busy))
+}
+
+// enqueue adds a task to the worker queue, holding it in the overflow if the queue is full. It
+// never blocks, and reports whether the task was added to the overflow. It returns false for ok if
+// the task could not be added because the overflow budget of the pool is exhausted.
+func (w *Worker[T]) enqueue(task *Task[T]) (overflowed bool, ok bool) {
+	w.overflowMu.Lock()
+	// Once tasks overflow, later tasks must follow them to keep the worker's tasks in order.
+	if len(w.overflow) == 0 {
+		select {
+		case w.workerQueue <- task:
+			w.overflowMu.Unlock()
+			return false, true
+		default:
+		}
+	}
+	if !w.config.overflowBudget.tryAcquire() {
+		w.overflowMu.Unlock()
+		return false, false
+	}
+	w.overflow = append(w.overflow, task)
+	w.overflowMu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return true, true
+}
+
+// popOverflow removes the oldest task from the overflow, or returns nil if it is empty.
+func (w *Worker[T]) popOverflow() *Task[T] {
+	w.overflowMu.Lock()
+	defer w.overflowMu.Unlock()
+	if len(w.overflow) == 0 {
+		return nil
+	}
+	task := w.overflow[0]
+	w.overflow[0] = nil
+	w.overflow = w.overflow[1:]
+	w.config.overflowBudget.release()
+	return task
+}
+
+// stealOverflow removes the newest affinity-optional task from the overflow, or returns nil if
+// there is none. Stealing from the opposite end to popOverflow keeps contention with the owning
+// worker low.
+func (w *Worker[T]) stealOverflow() *Task[T] {
+	w.overflowMu.Lock()
+	defer w.overflowMu.Unlock()
+	for i := len(w.overflow) - 1; i >= 0; i-- {
+		if task := w.overflow[i]; task.AffinityOptional {
+			w.overflow = append(w.overflow[:i], w.overflow[i+1:]...)
+			w.config.overflowBudget.release()
+			return task
+		}
+	}
+	return nil
+}
+
+// signalSteal signals idle workers that an affinity-optional task may be stolen.
+func (w *Worker[T]) signalSteal() {
+	select {
+	case w.config.steal <- struct{}{}:
+	default:
+	}
+}
+
+// steal executes an affinity-optional task stolen from the overflow of another worker, and
+// reports whether one was found. The route key of a stolen task is not allocated to the worker.
+func (w *Worker[T]) steal(ctx context.Context, taskCtx context.Context) bool {
+	if w.config.steal == nil || w.config.workers == nil {
+		return false
+	}
+	for _, other := range w.config.workers.list() {
+		if other == w {
+			continue
+		}
+		if task := other.stealOverflow(); task != nil {
+			w.execute(ctx, taskCtx, task)
+			return true
+		}
+	}
+	return false
+}
+
+// HasRouteKey checks if the worker has been allocated to the given route key.
+func (w *Worker[T]) HasRouteKey(routeKey string) bool {
+	return w.routeKeys.Has(routeKey)
+}
+
+// Wait blocks for the worker result to be received.
+func (w *Worker[T]) Wait() *WorkerResult {
+	return <-w.done
+}
+
+// receive allocates the route key of a received task to the worker, unless ctx is cancelled and the
+// task will be skipped.
+func (w *Worker[T]) receive(ctx context.Context, task *Task[T]) {
+	if ctx.Err() != nil || w.routeKeys.Has(task.RouteKey) {
+		return
+	}
+	w.routeKeys.Add(task.RouteKey)
+	if w.config.workers != nil {
+		w.config.workers.allocate(task.RouteKey, w)
+	}
+}
+
+func (w *Worker[T]) execute(ctx context.Context, taskCtx context.Context, task *Task[T]) {
+	if ctx.Err() != nil {
+		w.workerResult.Skipped += 1
+		if w.config.progress != nil {
+			w.config.progress.skip()
+		}
+		return
+	}
+
+	atomic.StoreInt32(&w.busy, 1)
+	defer atomic.StoreInt32(&w.busy, 0)
+
+	if w.config.progress != nil {
+		w.config.progress.start()
+	}
+
+	warmup := w.config.warmup.next()
+
+	start := time.Now()
+	if !task.Scheduled.IsZero() && !warmup {
+		w.recordScheduleLag(start.Sub(task.Scheduled))
+	}
+
+	value, err := task.Func(taskCtx)
+
+	end := time.Now()
+	duration := end.Sub(start)
+
+	latency := duration
+	if !task.Scheduled.IsZero() {
+		latency = end.Sub(task.Scheduled)
+	}
+
+	if warmup {
+		w.recordWarmup(duration, latency, err)
+	} else {
+		w.workerResult.Completed += 1
+		w.workerResult.TotalDuration += duration
+		w.workerResult.Latencies.Record(latency)
+		if err != nil {
+			w.recordError(err)
+		}
+
+		if w.config.RouteKeyStats {
+			w.recordRouteKey(task.RouteKey, latency, err)
+		}
+	}
+
+	if w.config.Timeline != nil {
+		w.config.Timeline.Record(end, latency, err != nil)
+	}
+
+	if w.config.progress != nil {
+		w.config.progress.complete(latency, err)
+	}
+
+	if w.config.OnResult != nil {
+		w.config.OnResult(TaskResult[T]{
+			Task:     task,
+			WorkerID: w.config.ID,
+			Start:    start,
+			End:      end,
+			Value:    value,
+			Err:      err,
+			Warmup:   warmup,
+		})
+	}
+}
+
+func (w *Worker[T]) recordError(err error) {
+	w.workerResult.Errors += 1
+	if w.config.isTimeout != nil && w.config.isTimeout(err) {
+		w.workerResult.Timeouts += 1
+	}
+
+	if len(w.workerResult.ErrorSamples) >= maxErrorSamples {
+		return
+	}
+	for _, sample := range w.workerResult.ErrorSamples {
+		if sample.Error() == err.Error() {
+			return
+		}
+	}
+	w.workerResult.ErrorSamples = append(w.workerResult.ErrorSamples, err)
+}
+
+func (w *Worker[T]) recordWarmup(duration time.Duration, latency time.Duration, err error) {
+	if w.workerResult.Warmup == nil {
+		w.workerResult.Warmup = newWarmupResult(w.config.Histogram)
+	}
+
+	w.workerResult.Warmup.Completed += 1
+	w.workerResult.Warmup.TotalDuration += duration
+	w.workerResult.Warmup.Latencies.Record(latency)
+	if err != nil {
+		w.workerResult.Warmup.Errors += 1
+	}
+}
+
+func (w *Worker[T]) recordScheduleLag(lag time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+	if w.workerResult.ScheduleLag == nil {
+		w.workerResult.ScheduleLag = stats.NewHistogram(w.config.Histogram)
+	}
+	w.workerResult.ScheduleLag.Record(lag)
+
+	if w.config.progress != nil {
+		w.config.progress.lag(lag)
+	}
+}
+
+func (w *Worker[T]) recordRouteKey(routeKey string, duration time.Duration, err error) {
+	result, ok := w.workerResult.RouteKeyResults[routeKey]
+	if !ok {
+		result = newRouteKeyResult(w.config.Histogram)
+		w.workerResult.RouteKeyResults[routeKey] = result
+	}
+
+	result.Completed += 1
+	if err != nil {
+		result.Errors += 1
+	}
+	result.Latencies.Record(duration)
+}
+
+func newRouteKeyResult(config stats.HistogramConfig) *RouteKeyResult {
+	return &RouteKeyResult{Latencies: stats.NewHistogram(config)}
+}
+
+func newWarmupResult(config stats.HistogramConfig) *WarmupResult {
+	return &WarmupResult{Latencies: stats.NewHistogram(config)}
+}
+
+func (r *WarmupResult) merge(other *WarmupResult) {
+	r.Completed += other.Completed
+	r.Errors += other.Errors
+	r.TotalDuration += other.TotalDuration
+	r.Latencies.Merge(other.Latencies)
+}
+
+func (r *RouteKeyResult) merge(other *RouteKeyResult) {
+	r.Completed += other.Completed
+	r.Errors += other.Errors
+	r.Latencies.Merge(other.Latencies)
+}
+
+// IdleDuration returns the time the worker spent waiting for tasks between starting and stopping.
+func (r *WorkerResult) IdleDuration() time.Duration {
+	idle := r.Stopped.Sub(r.Started) - r.TotalDuration
+	if r.Warmup != nil {
+		idle -= r.Warmup.TotalDuration
+	}
+	return idle
+}
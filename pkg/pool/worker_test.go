@@ -0,0 +1,183 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPool_Worker(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{
+			name:    "task execution success",
+			wantErr: nil,
+		},
+		{
+			name:    "task execution error",
+			wantErr: errors.New("some error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskQueue := make(chan *Task[any])
+			worker := newWorker(workerConfig[any]{ID: 1, QueueSize: 10}, taskQueue)
+			worker.Start(context.Background())
+			task := &Task[any]{
+				Func: func(ctx context.Context) (any, error) {
+					return nil, tt.wantErr
+				},
+			}
+			worker.Submit(task)
+			close(taskQueue)
+			result := worker.Wait()
+			assert.Equal(t, 1, result.WorkerID)
+			assert.Equal(t, 1, result.Completed)
+			assert.Equal(t, int64(1), result.Latencies.Count())
+			assert.NotEmpty(t, result.TotalDuration)
+			assert.False(t, result.Stopped.Before(result.Started))
+			assert.GreaterOrEqual(t, result.IdleDuration(), time.Duration(0))
+
+			if tt.wantErr != nil {
+				assert.Equal(t, 1, result.Errors)
+				require.Len(t, result.ErrorSamples, 1)
+				assert.EqualError(t, result.ErrorSamples[0], tt.wantErr.Error())
+			} else {
+				assert.Zero(t, result.Errors)
+				assert.Empty(t, result.ErrorSamples)
+			}
+		})
+	}
+}
+
+func TestWorker_errors(t *testing.T) {
+	taskQueue := make(chan *Task[any])
+	worker := newWorker(workerConfig[any]{
+		QueueSize: 10,
+		isTimeout: func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+	}, taskQueue)
+	worker.Start(context.Background())
+
+	var errs []error
+	for i := 0; i < 5; i++ {
+		errs = append(errs, context.DeadlineExceeded)
+	}
+	for i := 0; i < maxErrorSamples*2; i++ {
+		errs = append(errs, fmt.Errorf("error %d", i))
+	}
+	for _, err := range errs {
+		err := err
+		taskQueue <- &Task[any]{
+			Func: func(ctx context.Context) (any, error) {
+				return nil, err
+			},
+		}
+	}
+	close(taskQueue)
+
+	result := worker.Wait()
+	assert.Equal(t, len(errs), result.Errors)
+	assert.Equal(t, 5, result.Timeouts)
+	require.Len(t, result.ErrorSamples, maxErrorSamples)
+	assert.Equal(t, context.DeadlineExceeded, result.ErrorSamples[0])
+	assert.EqualError(t, result.ErrorSamples[1], "error 0")
+}
+
+func TestWorker_routeKeys(t *testing.T) {
+	taskQueue := make(chan *Task[any])
+	worker := newWorker(workerConfig[any]{QueueSize: 10}, taskQueue)
+	worker.Start(context.Background())
+
+	for _, routeKey := range []string{"a", "b", "a"} {
+		taskQueue <- &Task[any]{
+			RouteKey: routeKey,
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		}
+	}
+	close(taskQueue)
+
+	result := worker.Wait()
+	assert.True(t, worker.HasRouteKey("a"))
+	assert.True(t, worker.HasRouteKey("b"))
+	assert.Equal(t, 2, result.RouteKeys)
+	assert.Equal(t, 3, result.Completed)
+}
+
+func TestWorker_routeKeyStats(t *testing.T) {
+	taskQueue := make(chan *Task[any])
+	worker := newWorker(workerConfig[any]{QueueSize: 10, RouteKeyStats: true}, taskQueue)
+	worker.Start(context.Background())
+
+	for _, routeKey := range []string{"a", "b", "a"} {
+		taskQueue <- &Task[any]{
+			RouteKey: routeKey,
+			Func: func(ctx context.Context) (any, error) {
+				return nil, nil
+			},
+		}
+	}
+	taskQueue <- &Task[any]{
+		RouteKey: "b",
+		Func: func(ctx context.Context) (any, error) {
+			return nil, errors.New("some error")
+		},
+	}
+	close(taskQueue)
+
+	result := worker.Wait()
+	assert.Len(t, result.RouteKeyResults, 2)
+	assert.Equal(t, 2, result.RouteKeyResults["a"].Completed)
+	assert.Equal(t, 0, result.RouteKeyResults["a"].Errors)
+	assert.Equal(t, int64(2), result.RouteKeyResults["a"].Latencies.Count())
+	assert.Equal(t, 2, result.RouteKeyResults["b"].Completed)
+	assert.Equal(t, 1, result.RouteKeyResults["b"].Errors)
+}
+
+func TestWorker_scheduledTask(t *testing.T) {
+	taskQueue := make(chan *Task[any])
+	worker := newWorker(workerConfig[any]{QueueSize: 10}, taskQueue)
+	worker.Start(context.Background())
+
+	behind := 50 * time.Millisecond
+	worker.Submit(&Task[any]{
+		RouteKey:  "a",
+		Scheduled: time.Now().Add(-behind),
+		Func: func(ctx context.Context) (any, error) {
+			return nil, nil
+		},
+	})
+	close(taskQueue)
+
+	result := worker.Wait()
+	assert.Less(t, result.TotalDuration, behind)
+	assert.GreaterOrEqual(t, result.Latencies.Min(), behind)
+	if assert.NotNil(t, result.ScheduleLag) {
+		assert.Equal(t, int64(1), result.ScheduleLag.Count())
+		assert.GreaterOrEqual(t, result.ScheduleLag.Min(), behind)
+	}
+}
+
+func TestWorker_unscheduledTaskNoScheduleLag(t *testing.T) {
+	taskQueue := make(chan *Task[any])
+	worker := newWorker(workerConfig[any]{QueueSize: 10}, taskQueue)
+	worker.Start(context.Background())
+
+	worker.Submit(&Task[any]{
+		Func: func(ctx context.Context) (any, error) {
+			return nil, nil
+		},
+	})
+	close(taskQueue)
+
+	result := worker.Wait()
+	assert.Nil(t, result.ScheduleLag)
+}
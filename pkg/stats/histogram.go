@@ -0,0 +1,143 @@
+// Package stats records latency distributions in HDR histograms and timelines.
+package stats
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"time"
+)
+
+const (
+	DefaultSignificantFigures = 2
+	DefaultMaxValue           = time.Hour
+)
+
+// HistogramConfig configures the range and precision of a Histogram. Durations are recorded
+// with microsecond resolution, and values greater than MaxValue are clamped to MaxValue.
+type HistogramConfig struct {
+	SignificantFigures int
+	MaxValue           time.Duration
+}
+
+func (c HistogramConfig) withDefaults() HistogramConfig {
+	if c.SignificantFigures == 0 {
+		c.SignificantFigures = DefaultSignificantFigures
+	}
+	if c.MaxValue == 0 {
+		c.MaxValue = DefaultMaxValue
+	}
+	return c
+}
+
+// Histogram is a high dynamic range histogram of durations. Memory usage is constant regardless
+// of the number of values recorded, and histograms created with the same config can be merged.
+// The exact min, max and sum of all recorded values are tracked alongside the histogram buckets.
+// A Histogram is not safe for concurrent use.
+type Histogram struct {
+	config HistogramConfig
+	hist   *hdrhistogram.Histogram
+	min    time.Duration
+	max    time.Duration
+	sum    time.Duration
+}
+
+// NewHistogram creates an empty histogram. Zero config values are replaced with defaults.
+func NewHistogram(config HistogramConfig) *Histogram {
+	config = config.withDefaults()
+	return &Histogram{
+		config: config,
+		hist:   hdrhistogram.New(1, config.MaxValue.Microseconds(), config.SignificantFigures),
+	}
+}
+
+// Record adds a duration to the histogram.
+func (h *Histogram) Record(d time.Duration) {
+	if h.Count() == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.sum += d
+
+	if d > h.config.MaxValue {
+		d = h.config.MaxValue
+	}
+	// Errors only occur for values outside the trackable range, which clamping prevents.
+	_ = h.hist.RecordValue(d.Microseconds())
+}
+
+// Merge adds all values recorded by other to the histogram.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.Count() == 0 {
+		return
+	}
+	if h.Count() == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.sum += other.sum
+	h.hist.Merge(other.hist)
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() int64 {
+	return h.hist.TotalCount()
+}
+
+// Min returns the exact smallest recorded value.
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the exact largest recorded value.
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Sum returns the exact sum of all recorded values.
+func (h *Histogram) Sum() time.Duration {
+	return h.sum
+}
+
+// Mean returns the exact mean of all recorded values.
+func (h *Histogram) Mean() time.Duration {
+	if h.Count() == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.Count())
+}
+
+// Percentile returns the p-th percentile (0 < p <= 100) of the recorded values, accurate to the
+// configured number of significant figures. Values that fall between two ranks are linearly
+// interpolated, so the 50th percentile of an even number of values is the mean of the two middle
+// values.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	n := h.Count()
+	if n == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(n-1)
+	lower := int64(rank)
+	v := h.valueAtRank(lower)
+	if frac := rank - float64(lower); frac > 0 && lower+1 < n {
+		v += time.Duration(frac * float64(h.valueAtRank(lower+1)-v))
+	}
+
+	// Bucket values are approximations, so keep them within the exact recorded bounds.
+	if v < h.min {
+		return h.min
+	}
+	if v > h.max {
+		return h.max
+	}
+	return v
+}
+
+// valueAtRank returns the recorded value with the given zero-based rank in ascending order.
+func (h *Histogram) valueAtRank(rank int64) time.Duration {
+	p := float64(rank+1) / float64(h.Count()) * 100
+	return time.Duration(h.hist.ValueAtPercentile(p)) * time.Microsecond
+}
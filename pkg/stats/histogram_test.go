@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Percentile(t *testing.T) {
+	h := NewHistogram(HistogramConfig{SignificantFigures: 3})
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 50, want: 500500 * time.Microsecond},
+		{p: 90, want: 900100 * time.Microsecond},
+		{p: 99, want: 990010 * time.Microsecond},
+		{p: 99.9, want: 999001 * time.Microsecond},
+		{p: 100, want: 1000 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		got := h.Percentile(tt.p)
+		assert.InEpsilon(t, tt.want, got, 0.001, "p%v", tt.p)
+	}
+
+	assert.Equal(t, int64(1000), h.Count())
+	assert.Equal(t, time.Millisecond, h.Min())
+	assert.Equal(t, time.Second, h.Max())
+	assert.Equal(t, 500500*time.Microsecond, h.Mean())
+}
+
+func TestHistogram_Percentile_interpolated(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{
+			name:   "single value",
+			values: []time.Duration{5 * time.Millisecond},
+			p:      99,
+			want:   5 * time.Millisecond,
+		},
+		{
+			name:   "median odd length",
+			values: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond},
+			p:      50,
+			want:   20 * time.Millisecond,
+		},
+		{
+			name:   "median even length",
+			values: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 40 * time.Millisecond},
+			p:      50,
+			want:   25 * time.Millisecond,
+		},
+		{
+			name:   "p95 interpolated",
+			values: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond},
+			p:      95,
+			want:   480 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistogram(HistogramConfig{})
+			for _, v := range tt.values {
+				h.Record(v)
+			}
+			assert.InEpsilon(t, tt.want, h.Percentile(tt.p), 0.01)
+		})
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	a := NewHistogram(HistogramConfig{})
+	b := NewHistogram(HistogramConfig{})
+	a.Record(2 * time.Millisecond)
+	a.Record(4 * time.Millisecond)
+	b.Record(time.Millisecond)
+	b.Record(9 * time.Millisecond)
+
+	a.Merge(b)
+
+	assert.Equal(t, int64(4), a.Count())
+	assert.Equal(t, time.Millisecond, a.Min())
+	assert.Equal(t, 9*time.Millisecond, a.Max())
+	assert.Equal(t, 16*time.Millisecond, a.Sum())
+}
+
+func TestHistogram_clampsToMaxValue(t *testing.T) {
+	h := NewHistogram(HistogramConfig{MaxValue: time.Second})
+	h.Record(time.Minute)
+
+	assert.Equal(t, int64(1), h.Count())
+	assert.Equal(t, time.Minute, h.Max())
+	assert.Equal(t, time.Minute, h.Percentile(99))
+}
+
+func TestHistogram_empty(t *testing.T) {
+	h := NewHistogram(HistogramConfig{})
+	assert.Zero(t, h.Count())
+	assert.Zero(t, h.Min())
+	assert.Zero(t, h.Mean())
+	assert.Zero(t, h.Percentile(50))
+}
@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// Timeline buckets completed tasks into fixed intervals relative to a start time, recording
+// the count, errors and latencies of each interval. A Timeline is safe for concurrent use.
+type Timeline struct {
+	mu        sync.Mutex
+	start     time.Time
+	interval  time.Duration
+	histogram HistogramConfig
+	buckets   []*TimelineBucket
+}
+
+// TimelineBucket holds the stats of tasks that completed within a single interval. Offset is the
+// start of the interval relative to the start of the timeline.
+type TimelineBucket struct {
+	Offset    time.Duration
+	Completed int
+	Errors    int
+	Latencies *Histogram
+}
+
+// NewTimeline creates an empty timeline starting at start and bucketed by interval.
+func NewTimeline(start time.Time, interval time.Duration, histogram HistogramConfig) *Timeline {
+	return &Timeline{
+		start:     start,
+		interval:  interval,
+		histogram: histogram,
+	}
+}
+
+// Interval returns the size of each timeline bucket.
+func (t *Timeline) Interval() time.Duration {
+	return t.interval
+}
+
+// Record adds a task that completed at end and took d to execute to the timeline.
+func (t *Timeline) Record(end time.Time, d time.Duration, failed bool) {
+	i := int(end.Sub(t.start) / t.interval)
+	if i < 0 {
+		i = 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for len(t.buckets) <= i {
+		t.buckets = append(t.buckets, nil)
+	}
+
+	bucket := t.buckets[i]
+	if bucket == nil {
+		bucket = &TimelineBucket{
+			Offset:    time.Duration(i) * t.interval,
+			Latencies: NewHistogram(t.histogram),
+		}
+		t.buckets[i] = bucket
+	}
+
+	bucket.Completed += 1
+	if failed {
+		bucket.Errors += 1
+	}
+	bucket.Latencies.Record(d)
+}
+
+// Buckets returns every interval from the start of the timeline up to the last recorded task.
+// Intervals without any completed tasks have an empty histogram.
+func (t *Timeline) Buckets() []*TimelineBucket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buckets := make([]*TimelineBucket, len(t.buckets))
+	for i, bucket := range t.buckets {
+		if bucket == nil {
+			bucket = &TimelineBucket{
+				Offset:    time.Duration(i) * t.interval,
+				Latencies: NewHistogram(t.histogram),
+			}
+		}
+		buckets[i] = bucket
+	}
+
+	return buckets
+}
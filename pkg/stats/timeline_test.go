@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	start := time.Now()
+	timeline := NewTimeline(start, time.Second, HistogramConfig{})
+
+	timeline.Record(start.Add(100*time.Millisecond), 10*time.Millisecond, false)
+	timeline.Record(start.Add(900*time.Millisecond), 20*time.Millisecond, true)
+	timeline.Record(start.Add(2500*time.Millisecond), 30*time.Millisecond, false)
+
+	buckets := timeline.Buckets()
+	require.Len(t, buckets, 3)
+
+	assert.Equal(t, time.Duration(0), buckets[0].Offset)
+	assert.Equal(t, 2, buckets[0].Completed)
+	assert.Equal(t, 1, buckets[0].Errors)
+	assert.Equal(t, 20*time.Millisecond, buckets[0].Latencies.Max())
+
+	assert.Equal(t, time.Second, buckets[1].Offset)
+	assert.Equal(t, 0, buckets[1].Completed)
+	assert.Zero(t, buckets[1].Latencies.Count())
+
+	assert.Equal(t, 2*time.Second, buckets[2].Offset)
+	assert.Equal(t, 1, buckets[2].Completed)
+	assert.Equal(t, 0, buckets[2].Errors)
+}
+
+func TestTimeline_concurrentRecord(t *testing.T) {
+	start := time.Now()
+	timeline := NewTimeline(start, time.Millisecond, HistogramConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			timeline.Record(start.Add(time.Duration(i%10)*time.Millisecond), time.Millisecond, false)
+		}(i)
+	}
+	wg.Wait()
+
+	var completed int
+	for _, bucket := range timeline.Buckets() {
+		completed += bucket.Completed
+	}
+	assert.Equal(t, 100, completed)
+}